@@ -1,59 +1,96 @@
 package raft
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/urfave/cli/v2"
+
+	"github.com/golem-base/op-conductor-init/pkg/store"
 )
 
+// BackupManifestFile is the machine-readable manifest written into every backup
+const BackupManifestFile = "backup-manifest.json"
+
+// BackupManifest describes a backup. It is the output of `raft backup` and is
+// also written to backup-manifest.json inside the backup directory.
+type BackupManifest struct {
+	CreatedAt       time.Time    `json:"createdAt" yaml:"createdAt"`
+	SourceDirectory string       `json:"sourceDirectory" yaml:"sourceDirectory"`
+	BackupPath      string       `json:"backupPath" yaml:"backupPath"`
+	Files           []BackupFile `json:"files" yaml:"files"`
+}
+
+// BackupFile is a single file in a backup, relative to the backup directory
+type BackupFile struct {
+	Path string `json:"path" yaml:"path"`
+	Size int64  `json:"size" yaml:"size"`
+}
+
 // BackupAction handles the backup subcommand
 func BackupAction(ctx *cli.Context) error {
 	stateDir := ctx.String("state-dir")
 	backupDir := ctx.String("backup-dir")
 
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	out := textOutput(format)
+
 	// Create timestamp for backup
-	timestamp := time.Now().Format("20060102-150405")
+	createdAt := time.Now()
+	timestamp := createdAt.Format("20060102-150405")
 	backupPath := filepath.Join(backupDir, fmt.Sprintf("raft-backup-%s", timestamp))
 
-	fmt.Printf("Creating backup of Raft state...\n")
-	fmt.Printf("Source: %s\n", stateDir)
-	fmt.Printf("Destination: %s\n", backupPath)
-
-	// Create backup directory
-	if err := os.MkdirAll(backupPath, 0o755); err != nil {
-		return fmt.Errorf("failed to create backup directory: %w", err)
-	}
+	fmt.Fprintf(out, "Creating backup of Raft state...\n")
+	fmt.Fprintf(out, "Source: %s\n", stateDir)
+	fmt.Fprintf(out, "Destination: %s\n", backupPath)
 
-	// Find all .db files in state directory
+	// Find all state files in state directory
 	var filesToBackup []string
-	err := filepath.Walk(stateDir, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(stateDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && filepath.Ext(path) == ".db" {
+		if !info.IsDir() && isStateFile(stateDir, path) {
 			filesToBackup = append(filesToBackup, path)
 		}
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("failed to scan state directory: %w", err)
+		return readError(fmt.Errorf("failed to scan state directory: %w", err))
 	}
 
 	if len(filesToBackup) == 0 {
-		return fmt.Errorf("no state files found in %s", stateDir)
+		return exitError(ExitNotFound, fmt.Errorf("no state files found in %s", stateDir))
+	}
+
+	// Create backup directory
+	if err := os.MkdirAll(backupPath, 0o755); err != nil {
+		return exitError(ExitIO, fmt.Errorf("failed to create backup directory: %w", err))
+	}
+
+	manifest := &BackupManifest{
+		CreatedAt:       createdAt.UTC(),
+		SourceDirectory: stateDir,
+		BackupPath:      backupPath,
+		Files:           []BackupFile{},
 	}
 
 	// Backup each file
-	fmt.Printf("\nBacking up %d files:\n", len(filesToBackup))
+	fmt.Fprintf(out, "\nBacking up %d files:\n", len(filesToBackup))
 	for _, srcPath := range filesToBackup {
 		// Calculate relative path from state directory
 		relPath, err := filepath.Rel(stateDir, srcPath)
 		if err != nil {
-			return fmt.Errorf("failed to calculate relative path: %w", err)
+			return exitError(ExitFailure, fmt.Errorf("failed to calculate relative path: %w", err))
 		}
 
 		// Create destination path
@@ -62,26 +99,35 @@ func BackupAction(ctx *cli.Context) error {
 		// Create destination directory if needed
 		dstDir := filepath.Dir(dstPath)
 		if err := os.MkdirAll(dstDir, 0o755); err != nil {
-			return fmt.Errorf("failed to create destination directory: %w", err)
+			return exitError(ExitIO, fmt.Errorf("failed to create destination directory: %w", err))
 		}
 
 		// Copy file
-		if err := copyFile(srcPath, dstPath); err != nil {
-			return fmt.Errorf("failed to backup %s: %w", relPath, err)
+		size, err := copyFile(srcPath, dstPath)
+		if err != nil {
+			return exitError(ExitIO, fmt.Errorf("failed to backup %s: %w", relPath, err))
 		}
+		manifest.Files = append(manifest.Files, BackupFile{Path: relPath, Size: size})
 
-		fmt.Printf("  ✓ %s\n", relPath)
+		fmt.Fprintf(out, "  ✓ %s\n", relPath)
 	}
 
 	// Create metadata file
 	metadataPath := filepath.Join(backupPath, "backup-metadata.txt")
 	metadata := fmt.Sprintf("Backup created: %s\nSource directory: %s\nFiles backed up: %d\n",
-		time.Now().Format(time.RFC3339),
+		createdAt.Format(time.RFC3339),
 		stateDir,
 		len(filesToBackup))
 
 	if err := os.WriteFile(metadataPath, []byte(metadata), 0o644); err != nil {
-		return fmt.Errorf("failed to write metadata: %w", err)
+		return exitError(ExitIO, fmt.Errorf("failed to write metadata: %w", err))
+	}
+	if err := writeBackupManifest(backupPath, manifest); err != nil {
+		return exitError(ExitIO, err)
+	}
+
+	if format != FormatText {
+		return writeReport(format, manifest)
 	}
 
 	fmt.Printf("\n✓ Backup completed successfully\n")
@@ -90,32 +136,77 @@ func BackupAction(ctx *cli.Context) error {
 	return nil
 }
 
-// copyFile copies a file from src to dst
-func copyFile(src, dst string) error {
+// isStateFile reports whether path below root is raft state: a .db store or a file of a snapshot,
+// which holds the cluster configuration of state generated in snapshot mode
+func isStateFile(root, path string) bool {
+	if filepath.Ext(path) == ".db" {
+		return true
+	}
+	relPath, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	dirs := strings.Split(filepath.Dir(relPath), string(filepath.Separator))
+	return slices.Contains(dirs, store.SnapshotsDir)
+}
+
+// writeBackupManifest writes the manifest into the backup directory
+func writeBackupManifest(backupPath string, manifest *BackupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(backupPath, BackupManifestFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	return nil
+}
+
+// readBackupManifest reads the manifest of a backup directory.
+// It returns nil when the backup has no manifest.
+func readBackupManifest(backupPath string) (*BackupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(backupPath, BackupManifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+
+	manifest := &BackupManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode backup manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// copyFile copies a file from src to dst and returns the number of bytes copied
+func copyFile(src, dst string) (int64, error) {
 	sourceFile, err := os.Open(src)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer sourceFile.Close()
 
 	// Get source file info
 	sourceInfo, err := sourceFile.Stat()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Create destination file
 	destFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, sourceInfo.Mode())
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer destFile.Close()
 
 	// Copy contents
-	if _, err := io.Copy(destFile, sourceFile); err != nil {
-		return err
+	size, err := io.Copy(destFile, sourceFile)
+	if err != nil {
+		return 0, err
 	}
 
 	// Sync to ensure data is written
-	return destFile.Sync()
+	return size, destFile.Sync()
 }
@@ -0,0 +1,76 @@
+package raft
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/urfave/cli/v2"
+
+	"github.com/golem-base/op-conductor-init/pkg/flags"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+func newBackupContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+	set := flag.NewFlagSet("backup", flag.ContinueOnError)
+	for _, f := range []cli.Flag{flags.StateDirFlag, flags.BackupDirFlag, flags.RestoreForceFlag, flags.FormatFlag} {
+		if err := f.Apply(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestBackupRestoreSnapshotModeNode(t *testing.T) {
+	stateDir := t.TempDir()
+	configuration := raft.Configuration{Servers: []raft.Server{
+		{Suffrage: raft.Voter, ID: "sequencer-1", Address: "sequencer-1:50050"},
+		{Suffrage: raft.Voter, ID: "sequencer-2", Address: "sequencer-2:50050"},
+	}}
+	// A node generated in snapshot mode keeps its membership only in the snapshot
+	if err := store.CreateStableStore(stateDir, "sequencer-1", 7, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateLogStore(stateDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateSnapshot(stateDir, 1000, 7, configuration, 1000, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	backupDir := t.TempDir()
+	if err := BackupAction(newBackupContext(t, "--state-dir", stateDir, "--backup-dir", backupDir, "--format", FormatJSON)); err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+	backups, err := os.ReadDir(backupDir)
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("Expected one backup, got %v, %v", backups, err)
+	}
+	backupPath := filepath.Join(backupDir, backups[0].Name())
+
+	// A snapshot taken after the backup must not outlive the restore
+	if err := store.CreateSnapshot(stateDir, 2000, 8, raft.Configuration{Servers: configuration.Servers[:1]}, 2000, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreAction(newBackupContext(t, "--state-dir", stateDir, "--backup-dir", backupPath, "--force", "--format", FormatJSON)); err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+
+	state, err := readNodeState(stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, index, ok := store.LatestConfiguration(state.logs, state.snapshots)
+	if !ok || index != 1000 || len(restored.Servers) != 2 {
+		t.Fatalf("Expected the backed up membership of 2 servers at index 1000, got %d at index %d", len(restored.Servers), index)
+	}
+	if len(state.snapshots) != 1 {
+		t.Fatalf("Expected only the backed up snapshot after restoring, got %d", len(state.snapshots))
+	}
+}
@@ -0,0 +1,300 @@
+package raft
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+// Checks performed by `raft verify --cluster-dir`, in the order they are reported
+const (
+	CheckReadable      = "readable"
+	CheckState         = "state"
+	CheckConfiguration = "configuration"
+	CheckLogRange      = "log-range"
+	CheckTerms         = "terms"
+	CheckVotes         = "votes"
+	CheckMembership    = "membership"
+)
+
+var clusterChecks = []string{
+	CheckReadable, CheckState, CheckConfiguration, CheckLogRange, CheckTerms, CheckVotes, CheckMembership,
+}
+
+// ClusterVerifyReport is the output of `raft verify --cluster-dir`: a pass/fail matrix
+// of every node against every check, and the problems behind each failure
+type ClusterVerifyReport struct {
+	Directory string              `json:"directory" yaml:"directory"`
+	Checks    []string            `json:"checks" yaml:"checks"`
+	Nodes     []ClusterNodeReport `json:"nodes" yaml:"nodes"`
+	Valid     bool                `json:"valid" yaml:"valid"`
+	Problems  []string            `json:"problems" yaml:"problems"`
+}
+
+// ClusterNodeReport is the row of a single node directory in the cluster matrix
+type ClusterNodeReport struct {
+	Node   string          `json:"node" yaml:"node"`
+	Valid  bool            `json:"valid" yaml:"valid"`
+	Checks map[string]bool `json:"checks" yaml:"checks"`
+}
+
+// clusterNode is a node directory and the state read from it
+type clusterNode struct {
+	name   string
+	report *StateReport
+	row    *ClusterNodeReport
+}
+
+// findNodeDirs returns the subdirectories of clusterDir that contain Raft state files
+func findNodeDirs(clusterDir string) ([]string, error) {
+	dirs, err := os.ReadDir(clusterDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan cluster directory: %w", err)
+	}
+
+	var nodes []string
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		for _, file := range []string{store.StableStoreFile, store.LogStoreFile} {
+			if _, err := os.Stat(filepath.Join(clusterDir, dir.Name(), file)); err == nil {
+				nodes = append(nodes, dir.Name())
+				break
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no node directories with raft state found in %s: %w", clusterDir, os.ErrNotExist)
+	}
+
+	sort.Strings(nodes)
+	return nodes, nil
+}
+
+// verifyCluster reads every node directory of the cluster and cross-checks them
+func verifyCluster(clusterDir string) (*ClusterVerifyReport, error) {
+	names, err := findNodeDirs(clusterDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ClusterVerifyReport{Directory: clusterDir, Checks: clusterChecks, Problems: []string{}}
+	fail := func(node *clusterNode, check, problem string, args ...interface{}) {
+		node.row.Checks[check] = false
+		report.Problems = append(report.Problems, fmt.Sprintf("%s: ", node.name)+fmt.Sprintf(problem, args...))
+	}
+
+	var rows []*ClusterNodeReport
+	var nodes []*clusterNode
+	for _, name := range names {
+		row := &ClusterNodeReport{Node: name, Checks: map[string]bool{}}
+		for _, check := range clusterChecks {
+			row.Checks[check] = true
+		}
+		rows = append(rows, row)
+		node := &clusterNode{name: name, row: row}
+
+		state, err := readNodeState(filepath.Join(clusterDir, name))
+		if err != nil {
+			// Nothing else can be checked for an unreadable node
+			for _, check := range clusterChecks {
+				node.row.Checks[check] = false
+			}
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		node.report = state.report()
+		for _, problem := range verifyState(node.report) {
+			fail(node, CheckState, "%s", problem)
+		}
+		nodes = append(nodes, node)
+	}
+
+	// Nodes are compared against the value most of them agree on
+	configurations := majority(nodes, configurationFingerprint)
+	for _, node := range nodes {
+		if fingerprint := configurationFingerprint(node.report); fingerprint != configurations {
+			fail(node, CheckConfiguration, "configuration entries %s differ from the cluster's %s", fingerprint, configurations)
+		}
+	}
+
+	logRanges := majority(nodes, logRangeFingerprint)
+	for _, node := range nodes {
+		if fingerprint := logRangeFingerprint(node.report); fingerprint != logRanges {
+			fail(node, CheckLogRange, "log range %s differs from the cluster's %s", fingerprint, logRanges)
+		}
+	}
+
+	currentTerms := majority(nodes, func(r *StateReport) string { return fmt.Sprint(r.StableStore.CurrentTerm) })
+	for _, node := range nodes {
+		if term := fmt.Sprint(node.report.StableStore.CurrentTerm); term != currentTerms {
+			fail(node, CheckTerms, "current term %s differs from the cluster's %s", term, currentTerms)
+		}
+	}
+	checkEntryTerms(nodes, fail)
+	checkVotes(nodes, fail)
+
+	for _, node := range nodes {
+		if node.report.Membership == nil {
+			fail(node, CheckMembership, "no cluster configuration found")
+			continue
+		}
+		found := false
+		for _, member := range node.report.Membership.Servers {
+			found = found || member.ID == node.name
+		}
+		if !found {
+			fail(node, CheckMembership, "node is not a member of its own configuration %v", formatMembers(node.report.Membership.Servers))
+		}
+	}
+
+	report.Valid = len(report.Problems) == 0
+	for _, row := range rows {
+		row.Valid = true
+		for _, passed := range row.Checks {
+			row.Valid = row.Valid && passed
+		}
+		report.Nodes = append(report.Nodes, *row)
+	}
+
+	return report, nil
+}
+
+// checkEntryTerms fails nodes whose log entries disagree with the cluster on the term at an index
+func checkEntryTerms(nodes []*clusterNode, fail func(*clusterNode, string, string, ...interface{})) {
+	votes := map[uint64]map[uint64]int{}
+	for _, node := range nodes {
+		for _, entry := range node.report.LogStore.Entries {
+			if entry.Type == unreadableEntryType {
+				continue
+			}
+			if votes[entry.Index] == nil {
+				votes[entry.Index] = map[uint64]int{}
+			}
+			votes[entry.Index][entry.Term]++
+		}
+	}
+
+	for _, node := range nodes {
+		for _, entry := range node.report.LogStore.Entries {
+			if entry.Type == unreadableEntryType {
+				continue
+			}
+			if expected := mostVoted(votes[entry.Index]); entry.Term != expected {
+				fail(node, CheckTerms, "entry %d has term %d, the cluster has term %d", entry.Index, entry.Term, expected)
+			}
+		}
+	}
+}
+
+// checkVotes fails nodes that recorded a vote for a different candidate than another node in the same term
+func checkVotes(nodes []*clusterNode, fail func(*clusterNode, string, string, ...interface{})) {
+	candidates := map[uint64]map[string]bool{}
+	for _, node := range nodes {
+		stable := node.report.StableStore
+		if stable.LastVoteCandidate == "" {
+			continue
+		}
+		if candidates[stable.LastVoteTerm] == nil {
+			candidates[stable.LastVoteTerm] = map[string]bool{}
+		}
+		candidates[stable.LastVoteTerm][stable.LastVoteCandidate] = true
+	}
+
+	for _, node := range nodes {
+		stable := node.report.StableStore
+		if stable.LastVoteCandidate == "" || len(candidates[stable.LastVoteTerm]) < 2 {
+			continue
+		}
+		others := make([]string, 0, len(candidates[stable.LastVoteTerm]))
+		for candidate := range candidates[stable.LastVoteTerm] {
+			others = append(others, candidate)
+		}
+		sort.Strings(others)
+		fail(node, CheckVotes, "voted for %s in term %d, but votes in that term went to %s",
+			stable.LastVoteCandidate, stable.LastVoteTerm, strings.Join(others, ", "))
+	}
+}
+
+// majority returns the fingerprint shared by most nodes, preferring the first node's on ties
+func majority(nodes []*clusterNode, fingerprint func(*StateReport) string) string {
+	counts := map[string]int{}
+	best, bestCount := "", 0
+	for _, node := range nodes {
+		value := fingerprint(node.report)
+		counts[value]++
+		if counts[value] > bestCount {
+			best, bestCount = value, counts[value]
+		}
+	}
+	return best
+}
+
+// mostVoted returns the term recorded by most nodes, preferring the lowest on ties
+func mostVoted(counts map[uint64]int) uint64 {
+	var best uint64
+	bestCount := 0
+	for term, count := range counts {
+		if count > bestCount || (count == bestCount && term < best) {
+			best, bestCount = term, count
+		}
+	}
+	return best
+}
+
+func configurationFingerprint(report *StateReport) string {
+	var entries []string
+	for _, entry := range report.LogStore.Entries {
+		if entry.Type == getLogTypeName(raft.LogConfiguration) {
+			entries = append(entries, fmt.Sprintf("%d@%d%v", entry.Index, entry.Term, formatMembers(entry.Members)))
+		}
+	}
+	for _, snapshot := range report.Snapshots {
+		if snapshot.Error == "" {
+			entries = append(entries, fmt.Sprintf("snapshot %d@%d%v", snapshot.ConfigurationIndex, snapshot.Term, formatMembers(snapshot.Members)))
+		}
+	}
+	return "[" + strings.Join(entries, " ") + "]"
+}
+
+func logRangeFingerprint(report *StateReport) string {
+	return fmt.Sprintf("%d-%d", report.LogStore.FirstIndex, report.LogStore.LastIndex)
+}
+
+// printClusterVerifyReport prints the cluster matrix in the human-readable text format
+func printClusterVerifyReport(report *ClusterVerifyReport) {
+	fmt.Printf("Verifying Raft cluster state in: %s\n\n", report.Directory)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "NODE\t%s\n", strings.ToUpper(strings.Join(report.Checks, "\t")))
+	for _, node := range report.Nodes {
+		cells := make([]string, 0, len(report.Checks))
+		for _, check := range report.Checks {
+			if node.Checks[check] {
+				cells = append(cells, "✓")
+			} else {
+				cells = append(cells, "✗")
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\n", node.Node, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+
+	if !report.Valid {
+		fmt.Printf("\n✗ Found %d problem(s):\n", len(report.Problems))
+		for _, problem := range report.Problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		return
+	}
+
+	fmt.Printf("\n✓ Raft state of all %d nodes is consistent\n", len(report.Nodes))
+}
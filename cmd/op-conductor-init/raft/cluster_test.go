@@ -0,0 +1,111 @@
+package raft
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/hashicorp/raft"
+
+	"github.com/golem-base/op-conductor-init/pkg/config"
+	"github.com/golem-base/op-conductor-init/pkg/generator"
+)
+
+func generateCluster(t *testing.T, outputDir, leader string) {
+	t.Helper()
+	cfg := &config.Config{
+		Nodes: []config.NodeConfig{
+			{ServerID: "sequencer-1", Address: "sequencer-1:50050", Suffrage: raft.Voter},
+			{ServerID: "sequencer-2", Address: "sequencer-2:50050", Suffrage: raft.Voter},
+			{ServerID: "sequencer-3", Address: "sequencer-3:50050", Suffrage: raft.Voter},
+		},
+		OutputDir:     outputDir,
+		InitialLeader: leader,
+		InitialTerm:   1,
+		InitialIndex:  1,
+		Mode:          config.ModeLog,
+	}
+	if err := generator.New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+}
+
+func failedChecks(t *testing.T, report *ClusterVerifyReport) map[string][]string {
+	t.Helper()
+	failed := map[string][]string{}
+	for _, node := range report.Nodes {
+		for _, check := range report.Checks {
+			if !node.Checks[check] {
+				failed[node.Node] = append(failed[node.Node], check)
+			}
+		}
+	}
+	return failed
+}
+
+func TestVerifyClusterConsistent(t *testing.T) {
+	clusterDir := filepath.Join(t.TempDir(), "raft-state")
+	generateCluster(t, clusterDir, "sequencer-1")
+
+	report, err := verifyCluster(clusterDir)
+	if err != nil {
+		t.Fatalf("Failed to verify cluster: %v", err)
+	}
+	if !report.Valid || len(report.Nodes) != 3 {
+		t.Fatalf("Expected 3 consistent nodes, got %+v", report)
+	}
+}
+
+func TestVerifyClusterConflictingVotes(t *testing.T) {
+	clusterDir := filepath.Join(t.TempDir(), "raft-state")
+	otherDir := filepath.Join(t.TempDir(), "other")
+	generateCluster(t, clusterDir, "sequencer-1")
+	generateCluster(t, otherDir, "sequencer-2")
+
+	// sequencer-2 now believes it won term 1, while sequencer-1 voted for itself in the same term
+	if err := os.RemoveAll(filepath.Join(clusterDir, "sequencer-2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(otherDir, "sequencer-2"), filepath.Join(clusterDir, "sequencer-2")); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := verifyCluster(clusterDir)
+	if err != nil {
+		t.Fatalf("Failed to verify cluster: %v", err)
+	}
+	if report.Valid {
+		t.Fatal("Expected conflicting votes to fail verification")
+	}
+
+	failed := failedChecks(t, report)
+	for _, node := range []string{"sequencer-1", "sequencer-2"} {
+		if len(failed[node]) != 1 || failed[node][0] != CheckVotes {
+			t.Errorf("Expected %s to fail only the votes check, failed %v", node, failed[node])
+		}
+	}
+	if len(failed["sequencer-3"]) != 0 {
+		t.Errorf("Expected sequencer-3 to pass, failed %v", failed["sequencer-3"])
+	}
+}
+
+func TestVerifyClusterUnknownNode(t *testing.T) {
+	clusterDir := filepath.Join(t.TempDir(), "raft-state")
+	generateCluster(t, clusterDir, "sequencer-1")
+
+	if err := os.Rename(filepath.Join(clusterDir, "sequencer-3"), filepath.Join(clusterDir, "sequencer-4")); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := verifyCluster(clusterDir)
+	if err != nil {
+		t.Fatalf("Failed to verify cluster: %v", err)
+	}
+
+	failed := failedChecks(t, report)
+	if len(failed["sequencer-4"]) != 1 || failed["sequencer-4"][0] != CheckMembership {
+		t.Errorf("Expected sequencer-4 to fail only the membership check, failed %v", failed["sequencer-4"])
+	}
+}
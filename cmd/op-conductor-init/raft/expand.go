@@ -0,0 +1,214 @@
+package raft
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/raft"
+	"github.com/urfave/cli/v2"
+
+	"github.com/golem-base/op-conductor-init/pkg/flags"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+// ExpandReport is the output of `raft expand`
+type ExpandReport struct {
+	ClusterDirectory string           `json:"clusterDirectory" yaml:"clusterDirectory"`
+	DryRun           bool             `json:"dryRun" yaml:"dryRun"`
+	Previous         MembershipReport `json:"previous" yaml:"previous"`
+	Membership       MembershipReport `json:"membership" yaml:"membership"`
+	Term             uint64           `json:"term" yaml:"term"`
+	Changes          []string         `json:"changes" yaml:"changes"`
+	// Updated are the existing nodes the configuration entry is appended to
+	Updated []string `json:"updated" yaml:"updated"`
+	// Lagging are existing nodes behind the others, they receive the entry from the leader
+	Lagging []string `json:"lagging" yaml:"lagging"`
+	// Created are the new node directories, cloned from Donor
+	Created []string `json:"created" yaml:"created"`
+	Donor   string   `json:"donor" yaml:"donor"`
+}
+
+// expansion is a planned `raft expand`
+type expansion struct {
+	*clusterChange
+	added  []raft.Server
+	report *ExpandReport
+}
+
+// ExpandAction handles the expand subcommand
+func ExpandAction(ctx *cli.Context) error {
+	clusterDir := ctx.String(flags.ClusterDirFlag.Name)
+	dryRun := ctx.Bool(flags.DryRunFlag.Name)
+
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	if clusterDir == "" {
+		return exitError(ExitUsage, fmt.Errorf("--%s is required", flags.ClusterDirFlag.Name))
+	}
+
+	change, err := parseMembershipChange(ctx)
+	if err != nil {
+		return exitError(ExitUsage, err)
+	}
+
+	plan, err := planExpansion(clusterDir, change.add)
+	if err != nil {
+		return err
+	}
+	plan.report.DryRun = dryRun
+
+	if !dryRun {
+		if err := plan.apply(); err != nil {
+			return writeError(err)
+		}
+	}
+
+	report := plan.report
+	if format != FormatText {
+		return writeReport(format, report)
+	}
+
+	fmt.Printf("Membership of %s (configuration index %d):\n", clusterDir, report.Previous.Index)
+	for _, change := range report.Changes {
+		fmt.Printf("  %s\n", change)
+	}
+	for _, name := range report.Lagging {
+		fmt.Printf("\n! %s is behind the other nodes and is left unchanged, it receives the new membership from the leader", name)
+	}
+	if len(report.Lagging) > 0 {
+		fmt.Println()
+	}
+	if dryRun {
+		fmt.Printf("\nDry run: would append configuration entry at index %d, term %d to %d node(s) and create %d node(s) from %s\n",
+			report.Membership.Index, report.Term, len(report.Updated), len(report.Created), report.Donor)
+		return nil
+	}
+	fmt.Printf("\n✓ Appended configuration entry at index %d, term %d to %d node(s)\n", report.Membership.Index, report.Term, len(report.Updated))
+	for _, name := range report.Created {
+		fmt.Printf("✓ Created %s from %s\n", filepath.Join(clusterDir, name), report.Donor)
+	}
+	// verify flags lagging nodes, which only catch up once the cluster runs
+	if len(report.Lagging) == 0 {
+		fmt.Println("Check the result with `raft verify --cluster-dir` before starting all nodes together")
+	}
+	return nil
+}
+
+// planExpansion plans the configuration entry adding the new servers to a stopped cluster, see
+// planClusterChange, and the new node directories cloned from the donor
+func planExpansion(clusterDir string, add []raft.Server) (*expansion, error) {
+	if len(add) == 0 {
+		return nil, exitError(ExitUsage, fmt.Errorf("no servers to add, set --%s", flags.AddServersFlag.Name))
+	}
+	change, err := planClusterChange(clusterDir, &membershipChange{add: add})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &expansion{
+		clusterChange: change,
+		added:         add,
+		report: &ExpandReport{
+			ClusterDirectory: clusterDir,
+			Previous:         MembershipReport{Index: change.previousIndex, Servers: memberReports(change.previous)},
+			Membership:       MembershipReport{Index: change.entry.Index, Servers: memberReports(change.next)},
+			Term:             change.entry.Term,
+			Changes:          diffMembership(change.previous, change.next),
+			Updated:          change.updated,
+			Lagging:          change.lagging,
+			Created:          []string{},
+			Donor:            change.donor,
+		},
+	}
+	for _, server := range add {
+		if _, err := os.Stat(filepath.Join(clusterDir, string(server.ID))); err == nil {
+			return nil, exitError(ExitConflict, fmt.Errorf("%s already exists in %s", server.ID, clusterDir))
+		}
+		plan.report.Created = append(plan.report.Created, string(server.ID))
+	}
+	return plan, nil
+}
+
+// apply first stages the new nodes in hidden directories of the cluster directory: each is cloned
+// from the donor and gets the configuration entry, so it starts with the same log including its own
+// membership. Only then the entry is appended to the up-to-date nodes and the new nodes are moved
+// into place. Staged nodes are removed on failure, and a failure after an existing node was touched
+// is returned as a partialError listing the nodes that may already be modified.
+func (e *expansion) apply() error {
+	donor := filepath.Join(e.clusterDir, e.donor)
+	staged := make(map[raft.ServerID]string)
+	defer func() {
+		for _, dir := range staged {
+			os.RemoveAll(dir)
+		}
+	}()
+	for _, server := range e.added {
+		dir, err := os.MkdirTemp(e.clusterDir, "."+string(server.ID)+".tmp-")
+		if err != nil {
+			return fmt.Errorf("failed to stage %s: %w", server.ID, err)
+		}
+		staged[server.ID] = dir
+		if err := os.Chmod(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to stage %s: %w", server.ID, err)
+		}
+		if err := store.CloneNode(donor, dir, string(server.ID), e.entry.Term); err != nil {
+			return fmt.Errorf("failed to create %s: %w", server.ID, err)
+		}
+		if err := store.AppendLog(dir, e.entry); err != nil {
+			return fmt.Errorf("failed to append configuration entry to %s: %w", server.ID, err)
+		}
+	}
+
+	modified, err := e.appendEntry()
+	if err != nil {
+		return partialFailure(modified, err)
+	}
+	for _, server := range e.added {
+		if err := os.Rename(staged[server.ID], filepath.Join(e.clusterDir, string(server.ID))); err != nil {
+			return partialFailure(modified, fmt.Errorf("failed to move %s into place: %w", server.ID, err))
+		}
+		delete(staged, server.ID)
+		modified = append(modified, string(server.ID))
+	}
+	return nil
+}
+
+// entryTerm returns the term of a node's log entry or snapshot at index. ok is false when the
+// node holds neither, because the index is beyond its log or compacted away.
+func entryTerm(state *nodeState, index uint64) (term uint64, ok bool, err error) {
+	for _, snapshot := range state.snapshots {
+		if snapshot.Err == nil && snapshot.Index == index {
+			return snapshot.Term, true, nil
+		}
+	}
+	if state.logs.LastIndex == 0 || index < state.logs.FirstIndex || index > state.logs.LastIndex {
+		return 0, false, nil
+	}
+	entry := state.logs.Entries[index-state.logs.FirstIndex]
+	if entry.Err != nil {
+		return 0, false, fmt.Errorf("failed to read log entry %d: %w", entry.Index, entry.Err)
+	}
+	return entry.Log.Term, true, nil
+}
+
+// lastEntry returns the index and term of the last log entry of a node, or of its latest snapshot
+// when the log is compacted up to it
+func lastEntry(state *nodeState) (uint64, uint64, error) {
+	var index, term uint64
+	for _, snapshot := range state.snapshots {
+		if snapshot.Err == nil && snapshot.Index > index {
+			index, term = snapshot.Index, snapshot.Term
+		}
+	}
+	if state.logs.LastIndex > index {
+		last := state.logs.Entries[len(state.logs.Entries)-1]
+		if last.Err != nil {
+			return 0, 0, fmt.Errorf("failed to read last log entry %d: %w", last.Index, last.Err)
+		}
+		index, term = last.Index, last.Log.Term
+	}
+	return index, term, nil
+}
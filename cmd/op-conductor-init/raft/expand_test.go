@@ -0,0 +1,154 @@
+package raft
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/urfave/cli/v2"
+
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+func TestExpandCluster(t *testing.T) {
+	clusterDir := filepath.Join(t.TempDir(), "raft-state")
+	generateCluster(t, clusterDir, "sequencer-1")
+
+	// sequencer-3 stopped before receiving the last entry
+	for _, node := range []string{"sequencer-1", "sequencer-2"} {
+		if err := store.AppendLog(filepath.Join(clusterDir, node), &raft.Log{Index: 2, Term: 3, Type: raft.LogNoop}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	add := []raft.Server{
+		{ID: "sequencer-4", Address: "sequencer-4:50050", Suffrage: raft.Voter},
+		{ID: "sequencer-5", Address: "sequencer-5:50050", Suffrage: raft.Voter},
+	}
+	plan, err := planExpansion(clusterDir, add)
+	if err != nil {
+		t.Fatalf("Failed to plan expansion: %v", err)
+	}
+	if plan.entry.Index != 3 || plan.entry.Term != 3 {
+		t.Fatalf("Expected entry at index 3, term 3, got index %d, term %d", plan.entry.Index, plan.entry.Term)
+	}
+	if len(plan.report.Updated) != 2 || len(plan.report.Lagging) != 1 || plan.report.Lagging[0] != "sequencer-3" {
+		t.Fatalf("Expected sequencer-3 to lag, got updated %v, lagging %v", plan.report.Updated, plan.report.Lagging)
+	}
+	if err := plan.apply(); err != nil {
+		t.Fatalf("Failed to expand cluster: %v", err)
+	}
+
+	for _, node := range []string{"sequencer-1", "sequencer-2", "sequencer-4", "sequencer-5"} {
+		state, err := readNodeState(filepath.Join(clusterDir, node))
+		if err != nil {
+			t.Fatal(err)
+		}
+		configuration, index, _ := store.LatestConfiguration(state.logs, state.snapshots)
+		if index != 3 || len(configuration.Servers) != 5 {
+			t.Fatalf("%s: expected 5 members at index 3, got %d at index %d", node, len(configuration.Servers), index)
+		}
+		if state.stable.CurrentTerm != 3 {
+			t.Fatalf("%s: expected current term 3, got %d", node, state.stable.CurrentTerm)
+		}
+	}
+	state, err := readNodeState(filepath.Join(clusterDir, "sequencer-4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.stable.HasVote {
+		t.Fatalf("Expected the new node not to carry a vote, found one for %s", state.stable.LastVoteCand)
+	}
+
+	if _, err := planExpansion(clusterDir, add); err == nil {
+		t.Fatal("Expected adding existing members to fail")
+	}
+}
+
+func TestExpandRejectsDivergedLaggingNode(t *testing.T) {
+	clusterDir := filepath.Join(t.TempDir(), "raft-state")
+	generateCluster(t, clusterDir, "sequencer-1")
+
+	// sequencer-3 wrote an entry in term 2 that the others replaced in term 3
+	for _, node := range []string{"sequencer-1", "sequencer-2"} {
+		for index := uint64(2); index <= 3; index++ {
+			if err := store.AppendLog(filepath.Join(clusterDir, node), &raft.Log{Index: index, Term: 3, Type: raft.LogNoop}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := store.AppendLog(filepath.Join(clusterDir, "sequencer-3"), &raft.Log{Index: 2, Term: 2, Type: raft.LogNoop}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := planExpansion(clusterDir, []raft.Server{{ID: "sequencer-4", Address: "sequencer-4:50050", Suffrage: raft.Voter}})
+	exit, ok := err.(cli.ExitCoder)
+	if !ok || exit.ExitCode() != ExitConflict {
+		t.Fatalf("Expected exit code %d for a diverged lagging node, got: %v", ExitConflict, err)
+	}
+}
+
+func TestExpandFailureReportsModifiedNodes(t *testing.T) {
+	add := []raft.Server{{ID: "sequencer-4", Address: "sequencer-4:50050", Suffrage: raft.Voter}}
+	plan := func(t *testing.T) (string, *expansion) {
+		clusterDir := filepath.Join(t.TempDir(), "raft-state")
+		generateCluster(t, clusterDir, "sequencer-1")
+		plan, err := planExpansion(clusterDir, add)
+		if err != nil {
+			t.Fatalf("Failed to plan expansion: %v", err)
+		}
+		return clusterDir, plan
+	}
+	// breakLogStore makes the log store of a node impossible to open
+	breakLogStore := func(t *testing.T, nodeDir string) {
+		path := filepath.Join(nodeDir, store.LogStoreFile)
+		if err := os.Remove(path); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Mkdir(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertNotCreated := func(t *testing.T, clusterDir string) {
+		entries, err := os.ReadDir(clusterDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("Expected only the three existing nodes after a failed expansion, got %d entries", len(entries))
+		}
+	}
+
+	t.Run("staging", func(t *testing.T) {
+		clusterDir, plan := plan(t)
+		breakLogStore(t, filepath.Join(clusterDir, plan.report.Donor))
+
+		err := plan.apply()
+		var partial *partialError
+		if err == nil || errors.As(err, &partial) {
+			t.Fatalf("Expected a failure before any node was modified, got: %v", err)
+		}
+		assertNotCreated(t, clusterDir)
+	})
+
+	t.Run("appending", func(t *testing.T) {
+		clusterDir, plan := plan(t)
+		breakLogStore(t, filepath.Join(clusterDir, "sequencer-2"))
+
+		err := plan.apply()
+		var partial *partialError
+		if !errors.As(err, &partial) {
+			t.Fatalf("Expected a partial failure, got: %v", err)
+		}
+		if len(partial.modified) != 2 || partial.modified[0] != "sequencer-1" || partial.modified[1] != "sequencer-2" {
+			t.Fatalf("Expected sequencer-1 and sequencer-2 to be reported as modified, got %v", partial.modified)
+		}
+		exit, ok := writeError(err).(cli.ExitCoder)
+		if !ok || exit.ExitCode() != ExitPartial {
+			t.Fatalf("Expected exit code %d, got: %v", ExitPartial, err)
+		}
+		assertNotCreated(t, clusterDir)
+	})
+}
@@ -25,11 +25,10 @@ func GenerateAction(ctx *cli.Context) error {
 		return fmt.Errorf("failed to read config: %w", err)
 	}
 
+	// Generate logs its own outcome, which with --ensure or --self may be that nothing was written
 	gen := generator.New(cfg, log)
 	if err := gen.Generate(context.Background()); err != nil {
 		return fmt.Errorf("failed to generate raft state: %w", err)
 	}
-
-	log.Info("Successfully generated Raft state", "output", cfg.OutputDir)
 	return nil
 }
@@ -1,167 +1,126 @@
 package raft
 
 import (
-	"encoding/binary"
 	"fmt"
-	"path/filepath"
+	"time"
 
 	"github.com/hashicorp/raft"
 	"github.com/urfave/cli/v2"
-	bolt "go.etcd.io/bbolt"
 )
 
 // InfoAction handles the info subcommand
 func InfoAction(ctx *cli.Context) error {
 	stateDir := ctx.String("state-dir")
 
-	fmt.Printf("=== Raft State Information ===\n")
-	fmt.Printf("Directory: %s\n\n", stateDir)
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
 
-	// Check stable store
-	fmt.Println("Stable Store (raft-stable.db):")
-	fmt.Println("------------------------------")
-	stablePath := filepath.Join(stateDir, "raft-stable.db")
-	if err := showStableStoreInfo(stablePath); err != nil {
-		return fmt.Errorf("error reading stable store: %w", err)
+	state, err := readNodeState(stateDir)
+	if err != nil {
+		return readError(err)
 	}
+	report := state.report()
 
-	fmt.Println("\nLog Store (raft-log.db):")
-	fmt.Println("------------------------")
-	logPath := filepath.Join(stateDir, "raft-log.db")
-	if err := showLogStoreInfo(logPath); err != nil {
-		return fmt.Errorf("error reading log store: %w", err)
+	if format != FormatText {
+		return writeReport(format, report)
 	}
 
+	printStateReport(report)
 	return nil
 }
 
-func showStableStoreInfo(path string) error {
-	db, err := bolt.Open(path, 0o600, &bolt.Options{ReadOnly: true})
-	if err != nil {
-		return fmt.Errorf("failed to open stable store: %w", err)
-	}
-	defer db.Close()
-
-	return db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte("conf"))
-		if bucket == nil {
-			return fmt.Errorf("conf bucket not found")
-		}
+// printStateReport prints the state report in the human-readable text format
+func printStateReport(report *StateReport) {
+	fmt.Printf("=== Raft State Information ===\n")
+	fmt.Printf("Directory: %s\n\n", report.Directory)
 
-		var currentTerm, lastVoteTerm uint64
-		var lastVoteCand string
-		var hasVoted bool
-
-		// Read all values
-		bucket.ForEach(func(k, v []byte) error {
-			key := string(k)
-			switch key {
-			case "CurrentTerm":
-				if len(v) == 8 {
-					currentTerm = binary.BigEndian.Uint64(v)
-				}
-			case "LastVoteTerm":
-				if len(v) == 8 {
-					lastVoteTerm = binary.BigEndian.Uint64(v)
-					hasVoted = true
-				}
-			case "LastVoteCand":
-				lastVoteCand = string(v)
-			}
-			return nil
-		})
-
-		fmt.Printf("  Current Term: %d\n", currentTerm)
-		if hasVoted {
-			fmt.Printf("  Last Vote Term: %d\n", lastVoteTerm)
-			fmt.Printf("  Last Vote Candidate: %s\n", lastVoteCand)
-			fmt.Printf("  Node Role: %s\n", determineRole(lastVoteCand))
-		} else {
-			fmt.Printf("  Node Role: Follower (no vote recorded)\n")
-		}
+	fmt.Println("Stable Store (raft-stable.db):")
+	fmt.Println("------------------------------")
+	stable := report.StableStore
+	fmt.Printf("  Current Term: %d\n", stable.CurrentTerm)
+	if stable.LastVoteCandidate != "" {
+		fmt.Printf("  Last Vote Term: %d\n", stable.LastVoteTerm)
+		fmt.Printf("  Last Vote Candidate: %s\n", stable.LastVoteCandidate)
+		fmt.Printf("  Node Role: %s\n", stable.Role)
+	} else {
+		fmt.Printf("  Node Role: %s (no vote recorded, leader decided by election)\n", stable.Role)
+	}
 
-		return nil
-	})
-}
+	fmt.Println("\nLog Store (raft-log.db):")
+	fmt.Println("------------------------")
+	fmt.Printf("  First Index: %d\n", report.LogStore.FirstIndex)
+	fmt.Printf("  Last Index: %d\n", report.LogStore.LastIndex)
+	fmt.Printf("  Total Entries: %d\n", len(report.LogStore.Entries))
 
-func showLogStoreInfo(path string) error {
-	db, err := bolt.Open(path, 0o600, &bolt.Options{ReadOnly: true})
-	if err != nil {
-		return fmt.Errorf("failed to open log store: %w", err)
+	fmt.Println("\n  Log Entries:")
+	if len(report.LogStore.Entries) == 0 {
+		fmt.Println("    (no entries)")
 	}
-	defer db.Close()
-
-	return db.View(func(tx *bolt.Tx) error {
-		// Check conf bucket
-		confBucket := tx.Bucket([]byte("conf"))
-		if confBucket != nil {
-			var firstIndex, lastIndex uint64
-			if v := confBucket.Get([]byte("FirstIndex")); len(v) == 8 {
-				firstIndex = binary.BigEndian.Uint64(v)
-			}
-			if v := confBucket.Get([]byte("LastIndex")); len(v) == 8 {
-				lastIndex = binary.BigEndian.Uint64(v)
-			}
-			fmt.Printf("  First Index: %d\n", firstIndex)
-			fmt.Printf("  Last Index: %d\n", lastIndex)
-			fmt.Printf("  Total Entries: %d\n", lastIndex-firstIndex+1)
+	for _, entry := range report.LogStore.Entries {
+		fmt.Printf("    Entry %d: term=%d, type=%s, size=%d bytes\n", entry.Index, entry.Term, entry.Type, entry.Size)
+		if len(entry.Members) > 0 {
+			fmt.Printf("      Cluster Members: %v\n", formatMembers(entry.Members))
 		}
-
-		// Check logs bucket
-		logsBucket := tx.Bucket([]byte("logs"))
-		if logsBucket == nil {
-			return fmt.Errorf("logs bucket not found")
+		if entry.Payload != nil {
+			fmt.Printf("      Unsafe Payload: %s\n", formatPayload(entry.Payload))
 		}
+		if entry.Error != "" {
+			fmt.Printf("      ERROR: %s\n", entry.Error)
+		}
+	}
 
-		fmt.Println("\n  Log Entries:")
-		entryCount := 0
-		var clusterMembers []string
-
-		logsBucket.ForEach(func(k, v []byte) error {
-			if len(k) == 8 && len(v) >= 17 {
-				index := binary.BigEndian.Uint64(k)
-				term := binary.BigEndian.Uint64(v[0:8])
-				// logIndex := binary.BigEndian.Uint64(v[8:16])  // Not used currently
-				logType := v[16]
-				dataLen := len(v) - 17
-
-				fmt.Printf("    Entry %d: term=%d, type=%s, size=%d bytes\n",
-					index, term, getLogTypeName(logType), dataLen)
-
-				// If it's a configuration entry, decode it
-				if logType == uint8(raft.LogConfiguration) && len(v) > 17 {
-					members := decodeConfiguration(v[17:])
-					if len(members) > 0 {
-						clusterMembers = members
-						fmt.Printf("      Cluster Members: %v\n", members)
-					}
-				}
-
-				entryCount++
+	if len(report.Snapshots) > 0 {
+		fmt.Println("\nSnapshots (snapshots/):")
+		fmt.Println("-----------------------")
+		for _, snapshot := range report.Snapshots {
+			if snapshot.Error != "" {
+				fmt.Printf("  Snapshot %s: ERROR: %s\n", snapshot.ID, snapshot.Error)
+				continue
 			}
-			return nil
-		})
-
-		if entryCount == 0 {
-			fmt.Println("    (no entries)")
+			fmt.Printf("  Snapshot %s: index=%d, term=%d, size=%d bytes\n",
+				snapshot.ID, snapshot.Index, snapshot.Term, snapshot.Size)
+			fmt.Printf("    Configuration Index: %d\n", snapshot.ConfigurationIndex)
+			fmt.Printf("    Cluster Members: %v\n", formatMembers(snapshot.Members))
 		}
+	}
 
-		if len(clusterMembers) > 0 {
-			fmt.Printf("\n  Current Cluster Configuration:\n")
-			fmt.Printf("    Total Members: %d\n", len(clusterMembers))
-			for i, member := range clusterMembers {
-				fmt.Printf("    Member %d: %s\n", i+1, member)
-			}
+	if membership := report.Membership; membership != nil {
+		fmt.Printf("\nCurrent Cluster Configuration (index %d):\n", membership.Index)
+		fmt.Printf("  Total Members: %d\n", len(membership.Servers))
+		for i, member := range formatMembers(membership.Servers) {
+			fmt.Printf("  Member %d: %s\n", i+1, member)
 		}
+	}
 
-		return nil
-	})
+	fmt.Println("\nLatest Unsafe Head (recorded on disk):")
+	head := report.UnsafeHead
+	if head == nil {
+		fmt.Println("  (no unsafe payload recorded)")
+		return
+	}
+	fmt.Printf("  Block Number: %d\n", head.BlockNumber)
+	fmt.Printf("  Block Hash: %s\n", head.BlockHash)
+	fmt.Printf("  Parent Hash: %s\n", head.ParentHash)
+	fmt.Printf("  Timestamp: %d (%s)\n", head.Timestamp, time.Unix(int64(head.Timestamp), 0).UTC().Format(time.RFC3339))
+	fmt.Printf("  Transactions: %d\n", head.TransactionCount)
+	if head.ParentBeaconRoot != "" {
+		fmt.Printf("  Parent Beacon Root: %s\n", head.ParentBeaconRoot)
+	}
+	if head.SourceSnapshot != "" {
+		fmt.Printf("  Source: snapshot %s\n", head.SourceSnapshot)
+	} else {
+		fmt.Printf("  Source: log entry %d\n", head.SourceIndex)
+	}
 }
 
-func getLogTypeName(logType uint8) string {
-	switch raft.LogType(logType) {
+func getLogTypeName(logType raft.LogType) string {
+	switch logType {
 	case raft.LogCommand:
 		return "Command"
+	case raft.LogNoop:
+		return "Noop"
 	case raft.LogConfiguration:
 		return "Configuration"
 	case raft.LogAddPeerDeprecated:
@@ -175,67 +134,33 @@ func getLogTypeName(logType uint8) string {
 	}
 }
 
-func determineRole(votedFor string) string {
-	if votedFor != "" {
+// determineRole infers the role a node starts in from its recorded vote.
+// The server ID is taken from the node directory name, as op-conductor lays out its storage.
+func determineRole(votedFor, serverID string) string {
+	switch votedFor {
+	case "":
+		return "Follower"
+	case serverID:
 		return "Leader (voted for self)"
+	default:
+		return fmt.Sprintf("Follower (voted for %s)", votedFor)
 	}
-	return "Follower"
 }
 
-func decodeConfiguration(data []byte) []string {
-	if len(data) < 1 {
-		return nil
+func formatPayload(payload *PayloadReport) string {
+	parentBeaconRoot := payload.ParentBeaconRoot
+	if parentBeaconRoot == "" {
+		parentBeaconRoot = "none"
 	}
+	return fmt.Sprintf("block=%d, hash=%s, parent=%s, timestamp=%d, txs=%d, parent_beacon_root=%s",
+		payload.BlockNumber, payload.BlockHash, payload.ParentHash,
+		payload.Timestamp, payload.TransactionCount, parentBeaconRoot)
+}
 
-	// Skip protocol version
-	pos := 1
-	var members []string
-
-	for pos < len(data) {
-		// Read suffrage (1 byte)
-		if pos >= len(data) {
-			break
-		}
-		suffrage := data[pos]
-		pos++
-
-		// Read ID length
-		if pos+8 > len(data) {
-			break
-		}
-		idLen := binary.BigEndian.Uint64(data[pos : pos+8])
-		pos += 8
-
-		// Read ID
-		if pos+int(idLen) > len(data) {
-			break
-		}
-		id := string(data[pos : pos+int(idLen)])
-		pos += int(idLen)
-
-		// Read address length
-		if pos+8 > len(data) {
-			break
-		}
-		addrLen := binary.BigEndian.Uint64(data[pos : pos+8])
-		pos += 8
-
-		// Read address
-		if pos+int(addrLen) > len(data) {
-			break
-		}
-		addr := string(data[pos : pos+int(addrLen)])
-		pos += int(addrLen)
-
-		suffrageName := "Unknown"
-		if suffrage == 0 {
-			suffrageName = "Voter"
-		} else if suffrage == 1 {
-			suffrageName = "NonVoter"
-		}
-
-		members = append(members, fmt.Sprintf("%s (%s) - %s", id, addr, suffrageName))
+func formatMembers(members []MemberReport) []string {
+	formatted := make([]string, 0, len(members))
+	for _, member := range members {
+		formatted = append(formatted, fmt.Sprintf("%s (%s) - %s", member.ID, member.Address, member.Suffrage))
 	}
-
-	return members
+	return formatted
 }
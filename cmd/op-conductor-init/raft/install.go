@@ -0,0 +1,109 @@
+package raft
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/golem-base/op-conductor-init/pkg/bundle"
+	"github.com/golem-base/op-conductor-init/pkg/config"
+	"github.com/golem-base/op-conductor-init/pkg/flags"
+)
+
+// InstallReport is the output of `raft install`
+type InstallReport struct {
+	Bundle           string           `json:"bundle" yaml:"bundle"`
+	StateDirectory   string           `json:"stateDirectory" yaml:"stateDirectory"`
+	Manifest         *bundle.Manifest `json:"manifest,omitempty" yaml:"manifest,omitempty"`
+	Installed        []string         `json:"installed" yaml:"installed"`
+	AlreadyInstalled bool             `json:"alreadyInstalled" yaml:"alreadyInstalled"`
+	// Skipped is set when --if-empty found existing state, Manifest is not read then
+	Skipped bool `json:"skipped" yaml:"skipped"`
+	// ConductorFlags are the recommended op-conductor flags, including --raft.storage.dir
+	ConductorFlags []config.ConductorFlag `json:"conductorFlags,omitempty" yaml:"conductorFlags,omitempty"`
+}
+
+// InstallAction handles the install subcommand
+func InstallAction(ctx *cli.Context) error {
+	bundlePath := ctx.String(flags.InstallBundleFlag.Name)
+	stateDir := ctx.String(flags.StateDirFlag.Name)
+
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	out := textOutput(format)
+
+	fmt.Fprintf(out, "Installing Raft state bundle...\n")
+	fmt.Fprintf(out, "Bundle: %s\n", bundlePath)
+	fmt.Fprintf(out, "Destination: %s\n", stateDir)
+
+	if _, err := os.Stat(bundlePath); err != nil {
+		return readError(fmt.Errorf("failed to open bundle: %w", err))
+	}
+
+	if existing := bundle.ExistingState(stateDir); ctx.Bool(flags.InstallIfEmptyFlag.Name) && len(existing) > 0 {
+		report := &InstallReport{Bundle: bundlePath, StateDirectory: stateDir, Installed: []string{}, Skipped: true}
+		if format != FormatText {
+			return writeReport(format, report)
+		}
+		fmt.Printf("\n✓ State directory already holds Raft state (%s), skipping install\n", strings.Join(existing, ", "))
+		return nil
+	}
+
+	result, err := bundle.Install(bundlePath, stateDir, ctx.Bool(flags.InstallForceFlag.Name))
+	switch {
+	case errors.Is(err, bundle.ErrChecksumMismatch):
+		return exitError(ExitVerifyFailed, err)
+	case errors.Is(err, bundle.ErrConflict):
+		return exitError(ExitConflict, err)
+	case errors.Is(err, bundle.ErrInvalidBundle), errors.Is(err, fs.ErrNotExist):
+		return readError(err)
+	case err != nil:
+		return exitError(ExitIO, err)
+	}
+
+	manifest := result.Manifest
+	node := config.NodeConfig{ServerID: manifest.ServerID, Address: manifest.Address}
+	conductorFlags, err := config.ConductorFlags(node, stateDir)
+	if err != nil {
+		return exitError(ExitUnreadable, err)
+	}
+
+	report := &InstallReport{
+		Bundle:           bundlePath,
+		StateDirectory:   stateDir,
+		Manifest:         manifest,
+		Installed:        result.Installed,
+		AlreadyInstalled: result.AlreadyInstalled,
+		ConductorFlags:   conductorFlags,
+	}
+	if format != FormatText {
+		return writeReport(format, report)
+	}
+
+	fmt.Printf("\nNode: %s (%s, %s)\n", manifest.ServerID, manifest.Address, manifest.Suffrage)
+	fmt.Printf("Generated: %s (initial leader %s, term %d, %s mode)\n",
+		manifest.GeneratedAt.Format("2006-01-02 15:04:05 UTC"), manifest.InitialLeader, manifest.InitialTerm, manifest.Mode)
+
+	if result.AlreadyInstalled {
+		fmt.Printf("\n✓ State directory already contains this bundle, nothing to do\n")
+	} else {
+		fmt.Printf("\nInstalled %d verified files:\n", len(result.Installed))
+		for _, file := range result.Installed {
+			fmt.Printf("  ✓ %s\n", file)
+		}
+	}
+
+	args := make([]string, len(conductorFlags))
+	for i, flag := range conductorFlags {
+		args[i] = flag.Arg()
+	}
+	fmt.Printf("\nRecommended op-conductor flags:\n  %s\n", strings.Join(args, " \\\n  "))
+
+	return nil
+}
@@ -0,0 +1,334 @@
+package raft
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/raft"
+	"github.com/urfave/cli/v2"
+
+	"github.com/golem-base/op-conductor-init/pkg/config"
+	"github.com/golem-base/op-conductor-init/pkg/flags"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+// MigrateReport is the output of `raft migrate-addresses`
+type MigrateReport struct {
+	ClusterDirectory string               `json:"clusterDirectory" yaml:"clusterDirectory"`
+	DryRun           bool                 `json:"dryRun" yaml:"dryRun"`
+	Addresses        []AddressChange      `json:"addresses" yaml:"addresses"`
+	Nodes            []MigratedNode       `json:"nodes" yaml:"nodes"`
+	Verification     *ClusterVerifyReport `json:"verification,omitempty" yaml:"verification,omitempty"`
+}
+
+// AddressChange is the address of a server before and after the migration
+type AddressChange struct {
+	ServerID string `json:"serverId" yaml:"serverId"`
+	Before   string `json:"before" yaml:"before"`
+	After    string `json:"after" yaml:"after"`
+}
+
+// MigratedNode counts the configurations rewritten in a node directory
+type MigratedNode struct {
+	Node      string `json:"node" yaml:"node"`
+	Entries   int    `json:"entries" yaml:"entries"`
+	Snapshots int    `json:"snapshots" yaml:"snapshots"`
+}
+
+// MigrateAction handles the migrate-addresses subcommand
+func MigrateAction(ctx *cli.Context) error {
+	clusterDir := ctx.String(flags.ClusterDirFlag.Name)
+	dryRun := ctx.Bool(flags.DryRunFlag.Name)
+
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	if clusterDir == "" {
+		return exitError(ExitUsage, fmt.Errorf("--%s is required", flags.ClusterDirFlag.Name))
+	}
+
+	rewrite, used, err := parseAddressRewrite(ctx)
+	if err != nil {
+		return exitError(ExitUsage, err)
+	}
+
+	report, err := planMigration(clusterDir, rewrite)
+	if err != nil {
+		return err
+	}
+	report.DryRun = dryRun
+	if unused := used(); len(unused) > 0 {
+		return exitError(ExitUsage, fmt.Errorf("--%s addresses not found in any configuration: %v", flags.AddressMapFlag.Name, unused))
+	}
+
+	switch {
+	case len(report.Addresses) == 0:
+	case dryRun:
+		if report.Verification, err = verifyMigration(clusterDir, report.Nodes, rewrite); err != nil {
+			return err
+		}
+	default:
+		if err := migrateNodes(clusterDir, report.Nodes, rewrite); err != nil {
+			return writeError(err)
+		}
+		if report.Verification, err = verifyCluster(clusterDir); err != nil {
+			return readError(err)
+		}
+	}
+
+	if format != FormatText {
+		if err := writeReport(format, report); err != nil {
+			return exitError(ExitIO, fmt.Errorf("failed to write report: %w", err))
+		}
+	} else {
+		printMigrateReport(report)
+	}
+
+	if report.Verification != nil && !report.Verification.Valid {
+		when := "after migration"
+		if dryRun {
+			when = "on the migrated copy of the cluster"
+		}
+		return exitError(ExitVerifyFailed, fmt.Errorf("raft cluster verification failed with %d problem(s) %s",
+			len(report.Verification.Problems), when))
+	}
+	return nil
+}
+
+// migrateNodes rewrites the addresses of one node after the other. A failure is returned as a
+// partialError listing the nodes migrated so far, including the one that failed.
+func migrateNodes(clusterDir string, nodes []MigratedNode, rewrite store.AddressRewrite) error {
+	var modified []string
+	for _, node := range nodes {
+		modified = append(modified, node.Node)
+		if _, err := store.RewriteAddresses(filepath.Join(clusterDir, node.Node), rewrite); err != nil {
+			return partialFailure(modified, fmt.Errorf("failed to migrate %s: %w", node.Node, err))
+		}
+	}
+	return nil
+}
+
+// verifyMigration migrates a temporary copy of the cluster and verifies it, so a dry run reports
+// the verification result the migration would get
+func verifyMigration(clusterDir string, nodes []MigratedNode, rewrite store.AddressRewrite) (*ClusterVerifyReport, error) {
+	dir, err := os.MkdirTemp("", "op-conductor-init-migrate-")
+	if err != nil {
+		return nil, exitError(ExitIO, fmt.Errorf("failed to create directory for verification: %w", err))
+	}
+	defer os.RemoveAll(dir)
+
+	for _, node := range nodes {
+		if err := copyTree(filepath.Join(clusterDir, node.Node), filepath.Join(dir, node.Node)); err != nil {
+			return nil, exitError(ExitIO, fmt.Errorf("failed to copy %s for verification: %w", node.Node, err))
+		}
+		if _, err := store.RewriteAddresses(filepath.Join(dir, node.Node), rewrite); err != nil {
+			return nil, exitError(ExitIO, fmt.Errorf("failed to migrate the copy of %s: %w", node.Node, err))
+		}
+	}
+
+	report, err := verifyCluster(dir)
+	if err != nil {
+		return nil, readError(err)
+	}
+	report.Directory = clusterDir
+	return report, nil
+}
+
+// copyTree copies the regular files and directories below src to dst
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case d.Type().IsRegular():
+			_, err := copyFile(path, target)
+			return err
+		}
+		return nil
+	})
+}
+
+// parseAddressRewrite builds the rewrite from --map or --address-regex and --address-replacement.
+// used returns the --map addresses that never matched, so typos are not silently ignored.
+func parseAddressRewrite(ctx *cli.Context) (store.AddressRewrite, func() []string, error) {
+	pairs := splitList(ctx.String(flags.AddressMapFlag.Name))
+	pattern := ctx.String(flags.AddressRegexFlag.Name)
+
+	switch {
+	case len(pairs) > 0 && pattern != "":
+		return nil, nil, fmt.Errorf("--%s and --%s are mutually exclusive", flags.AddressMapFlag.Name, flags.AddressRegexFlag.Name)
+	case pattern != "":
+		if !ctx.IsSet(flags.AddressReplacementFlag.Name) {
+			return nil, nil, fmt.Errorf("--%s requires --%s", flags.AddressRegexFlag.Name, flags.AddressReplacementFlag.Name)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --%s: %w", flags.AddressRegexFlag.Name, err)
+		}
+		replacement := ctx.String(flags.AddressReplacementFlag.Name)
+		rewrite := func(_ raft.ServerID, address raft.ServerAddress) raft.ServerAddress {
+			return raft.ServerAddress(re.ReplaceAllString(string(address), replacement))
+		}
+		return rewrite, func() []string { return nil }, nil
+	case len(pairs) == 0:
+		return nil, nil, fmt.Errorf("either --%s or --%s is required", flags.AddressMapFlag.Name, flags.AddressRegexFlag.Name)
+	}
+
+	var result *multierror.Error
+	mapping := make(map[raft.ServerAddress]raft.ServerAddress)
+	for _, pair := range pairs {
+		old, next, err := splitPair(pair)
+		if err != nil {
+			result = multierror.Append(result, fmt.Errorf("--%s: %w", flags.AddressMapFlag.Name, err))
+			continue
+		}
+		mapping[raft.ServerAddress(old)] = raft.ServerAddress(next)
+	}
+	if result != nil {
+		return nil, nil, result.ErrorOrNil()
+	}
+
+	matched := make(map[raft.ServerAddress]bool)
+	rewrite := func(_ raft.ServerID, address raft.ServerAddress) raft.ServerAddress {
+		if next, ok := mapping[address]; ok {
+			matched[address] = true
+			return next
+		}
+		return address
+	}
+	used := func() []string {
+		var unused []string
+		for old := range mapping {
+			if !matched[old] {
+				unused = append(unused, string(old))
+			}
+		}
+		sort.Strings(unused)
+		return unused
+	}
+	return rewrite, used, nil
+}
+
+// planMigration applies the rewrite to every configuration of every node in memory. Each rewritten
+// configuration must still be valid, and all problems are reported before anything is written.
+func planMigration(clusterDir string, rewrite store.AddressRewrite) (*MigrateReport, error) {
+	names, err := findNodeDirs(clusterDir)
+	if err != nil {
+		return nil, readError(err)
+	}
+
+	report := &MigrateReport{ClusterDirectory: clusterDir, Addresses: []AddressChange{}, Nodes: []MigratedNode{}}
+	seen := make(map[AddressChange]bool)
+	var result *multierror.Error
+	check := func(name, where string, configuration raft.Configuration) bool {
+		next, changed := store.RewriteConfiguration(configuration, rewrite)
+		if !changed {
+			return false
+		}
+		nodes := make([]config.NodeConfig, 0, len(next.Servers))
+		rewritten := make(map[string]bool)
+		for i, server := range next.Servers {
+			nodes = append(nodes, config.NodeConfig{ServerID: string(server.ID), Address: string(server.Address)})
+			change := AddressChange{
+				ServerID: string(server.ID),
+				Before:   string(configuration.Servers[i].Address),
+				After:    string(server.Address),
+			}
+			if change.Before == change.After {
+				continue
+			}
+			rewritten[change.ServerID] = true
+			if !seen[change] {
+				seen[change] = true
+				report.Addresses = append(report.Addresses, change)
+			}
+		}
+		if err := config.ValidateChangedNodes(nodes, rewritten); err != nil {
+			result = multierror.Append(result, fmt.Errorf("%s: rewritten %s is invalid: %w", name, where, err))
+		}
+		return true
+	}
+
+	for _, name := range names {
+		state, err := readNodeState(filepath.Join(clusterDir, name))
+		if err != nil {
+			return nil, readError(fmt.Errorf("%s: %w", name, err))
+		}
+
+		node := MigratedNode{Node: name}
+		for _, entry := range state.logs.Entries {
+			if entry.Err != nil {
+				return nil, exitError(ExitUnreadable, fmt.Errorf("%s: entry %d: %w", name, entry.Index, entry.Err))
+			}
+			if entry.Log.Type != raft.LogConfiguration {
+				continue
+			}
+			configuration, err := store.DecodeConfiguration(entry.Log.Data)
+			if err != nil {
+				return nil, exitError(ExitUnreadable, fmt.Errorf("%s: configuration entry %d: %w", name, entry.Index, err))
+			}
+			if check(name, fmt.Sprintf("configuration entry %d", entry.Index), configuration) {
+				node.Entries++
+			}
+		}
+		for _, snapshot := range state.snapshots {
+			if snapshot.Err != nil {
+				return nil, exitError(ExitUnreadable, fmt.Errorf("%s: snapshot %s: %w", name, snapshot.ID, snapshot.Err))
+			}
+			if check(name, "snapshot "+snapshot.ID, snapshot.Configuration) {
+				node.Snapshots++
+			}
+		}
+		report.Nodes = append(report.Nodes, node)
+	}
+
+	if result != nil {
+		return nil, exitError(ExitUsage, result.ErrorOrNil())
+	}
+	return report, nil
+}
+
+// printMigrateReport prints the migration report in the human-readable text format
+func printMigrateReport(report *MigrateReport) {
+	fmt.Printf("Migrating server addresses in: %s\n\n", report.ClusterDirectory)
+	if len(report.Addresses) == 0 {
+		fmt.Println("No configuration contains an address to migrate")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVER\tBEFORE\tAFTER")
+	for _, change := range report.Addresses {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", change.ServerID, change.Before, change.After)
+	}
+	w.Flush()
+
+	fmt.Println()
+	verb := "Rewrote"
+	if report.DryRun {
+		verb = "Dry run: would rewrite"
+	}
+	for _, node := range report.Nodes {
+		fmt.Printf("%s %d configuration entries and %d snapshot(s) of %s\n", verb, node.Entries, node.Snapshots, node.Node)
+	}
+
+	if report.Verification != nil {
+		fmt.Println()
+		printClusterVerifyReport(report.Verification)
+	}
+}
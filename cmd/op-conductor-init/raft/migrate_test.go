@@ -0,0 +1,150 @@
+package raft
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/golem-base/op-conductor-init/pkg/flags"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+func newMigrateContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+	set := flag.NewFlagSet("migrate-addresses", flag.ContinueOnError)
+	for _, f := range []cli.Flag{flags.AddressMapFlag, flags.AddressRegexFlag, flags.AddressReplacementFlag} {
+		if err := f.Apply(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestMigrateAddresses(t *testing.T) {
+	clusterDir := filepath.Join(t.TempDir(), "raft-state")
+	generateCluster(t, clusterDir, "sequencer-1")
+
+	rewrite, used, err := parseAddressRewrite(newMigrateContext(t, "--map",
+		"sequencer-1:50050=sequencer-1.ns2:50050,sequencer-2:50050=sequencer-2.ns2:50050,sequencer-9:50050=x:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := planMigration(clusterDir, rewrite)
+	if err != nil {
+		t.Fatalf("Failed to plan migration: %v", err)
+	}
+	if len(report.Addresses) != 2 || report.Addresses[0] != (AddressChange{ServerID: "sequencer-1", Before: "sequencer-1:50050", After: "sequencer-1.ns2:50050"}) {
+		t.Fatalf("Unexpected address changes: %+v", report.Addresses)
+	}
+	if unused := used(); len(unused) != 1 || unused[0] != "sequencer-9:50050" {
+		t.Fatalf("Expected sequencer-9:50050 to be reported as unused, got %v", unused)
+	}
+
+	for _, node := range report.Nodes {
+		if node.Entries != 1 {
+			t.Fatalf("%s: expected one configuration entry to be rewritten, got %d", node.Node, node.Entries)
+		}
+	}
+
+	// A dry run verifies a migrated copy and leaves the cluster as it is
+	verification, err := verifyMigration(clusterDir, report.Nodes, rewrite)
+	if err != nil {
+		t.Fatalf("Failed to verify the migration: %v", err)
+	}
+	if !verification.Valid || verification.Directory != clusterDir {
+		t.Fatalf("Expected the migrated copy of %s to verify, got %s: %v", clusterDir, verification.Directory, verification.Problems)
+	}
+	state, err := readNodeState(filepath.Join(clusterDir, "sequencer-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configuration, _, _ := store.LatestConfiguration(state.logs, state.snapshots); configuration.Servers[0].Address != "sequencer-1:50050" {
+		t.Fatalf("Expected the dry run to leave the cluster unchanged, got %s", configuration.Servers[0].Address)
+	}
+
+	if err := migrateNodes(clusterDir, report.Nodes, rewrite); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	verification, err = verifyCluster(clusterDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verification.Valid {
+		t.Fatalf("Expected the migrated cluster to verify, got %v", verification.Problems)
+	}
+
+	state, err = readNodeState(filepath.Join(clusterDir, "sequencer-3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	configuration, index, _ := store.LatestConfiguration(state.logs, state.snapshots)
+	if index != 1 || state.stable.CurrentTerm != 1 || configuration.Servers[1].ID != "sequencer-2" ||
+		configuration.Servers[1].Address != "sequencer-2.ns2:50050" || configuration.Servers[2].Address != "sequencer-3:50050" {
+		t.Fatalf("Unexpected configuration at index %d, term %d: %+v", index, state.stable.CurrentTerm, configuration.Servers)
+	}
+}
+
+func TestMigrateAddressesRejectsInvalidRewrites(t *testing.T) {
+	clusterDir := filepath.Join(t.TempDir(), "raft-state")
+	generateCluster(t, clusterDir, "sequencer-1")
+
+	rewrite, _, err := parseAddressRewrite(newMigrateContext(t, "--address-regex", `^sequencer-\d`, "--address-replacement", "sequencer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := planMigration(clusterDir, rewrite); err == nil || !strings.Contains(err.Error(), "duplicate address sequencer:50050") {
+		t.Fatalf("Expected colliding addresses to be rejected, got: %v", err)
+	}
+
+	_, _, err = parseAddressRewrite(newMigrateContext(t, "--map", "a:1=b:1", "--address-regex", "a"))
+	if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Fatalf("Expected --map and --address-regex to conflict, got: %v", err)
+	}
+	if _, _, err = parseAddressRewrite(newMigrateContext(t, "--address-regex", "a")); err == nil {
+		t.Fatal("Expected --address-regex without --address-replacement to fail")
+	}
+}
+
+func TestMigrateFailureReportsMigratedNodes(t *testing.T) {
+	clusterDir := filepath.Join(t.TempDir(), "raft-state")
+	generateCluster(t, clusterDir, "sequencer-1")
+
+	rewrite, _, err := parseAddressRewrite(newMigrateContext(t, "--address-regex", `:50050$`, "--address-replacement", ":50051"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := planMigration(clusterDir, rewrite)
+	if err != nil {
+		t.Fatalf("Failed to plan migration: %v", err)
+	}
+
+	// sequencer-2's log store cannot be opened for writing
+	path := filepath.Join(clusterDir, "sequencer-2", store.LogStoreFile)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrateNodes(clusterDir, report.Nodes, rewrite)
+	var partial *partialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected a partial failure, got: %v", err)
+	}
+	if len(partial.modified) != 2 || partial.modified[0] != "sequencer-1" || partial.modified[1] != "sequencer-2" {
+		t.Fatalf("Expected sequencer-1 and sequencer-2 to be reported as migrated, got %v", partial.modified)
+	}
+	exit, ok := writeError(err).(cli.ExitCoder)
+	if !ok || exit.ExitCode() != ExitPartial {
+		t.Fatalf("Expected exit code %d, got: %v", ExitPartial, err)
+	}
+}
@@ -0,0 +1,127 @@
+package raft
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/golem-base/op-conductor-init/pkg/flags"
+)
+
+// Output formats supported by the raft inspection commands
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Exit codes returned by the raft commands, one per failure class
+const (
+	// ExitFailure is returned for unexpected failures that have no dedicated class
+	ExitFailure = 1
+	// ExitUsage is returned for invalid flags or flag combinations
+	ExitUsage = 2
+	// ExitNotFound is returned when a state directory, state file or backup does not exist
+	ExitNotFound = 3
+	// ExitUnreadable is returned when state files exist but cannot be opened or decoded
+	ExitUnreadable = 4
+	// ExitVerifyFailed is returned when verification completed and found problems
+	ExitVerifyFailed = 5
+	// ExitIO is returned when copying or writing files fails
+	ExitIO = 6
+	// ExitConflict is returned when an operation refuses to overwrite existing state
+	ExitConflict = 7
+	// ExitPartial is returned when writing failed after some nodes were already modified, leaving
+	// the cluster inconsistent until it is restored from backup
+	ExitPartial = 8
+)
+
+// exitError wraps err so that the process exits with the given code
+func exitError(code int, err error) error {
+	return cli.Exit(err.Error(), code)
+}
+
+// partialError is a write failure after some nodes of a cluster were already modified
+type partialError struct {
+	modified []string
+	err      error
+}
+
+func (e *partialError) Error() string {
+	return fmt.Sprintf("%v (already modified: %s, restore the cluster from backup before retrying)", e.err, strings.Join(e.modified, ", "))
+}
+
+func (e *partialError) Unwrap() error {
+	return e.err
+}
+
+// partialFailure returns err as a partialError once any node was modified
+func partialFailure(modified []string, err error) error {
+	if len(modified) == 0 {
+		return err
+	}
+	return &partialError{modified: modified, err: err}
+}
+
+// writeError classifies an error from modifying a cluster
+func writeError(err error) error {
+	var partial *partialError
+	if errors.As(err, &partial) {
+		return exitError(ExitPartial, err)
+	}
+	return exitError(ExitIO, err)
+}
+
+// readError classifies an error from reading state files
+func readError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return exitError(ExitNotFound, err)
+	}
+	return exitError(ExitUnreadable, err)
+}
+
+// outputFormat returns the validated --format value
+func outputFormat(ctx *cli.Context) (string, error) {
+	switch format := ctx.String(flags.FormatFlag.Name); format {
+	case FormatText, FormatJSON, FormatYAML:
+		return format, nil
+	default:
+		return "", exitError(ExitUsage, fmt.Errorf("unknown output format %q (expected %s, %s or %s)",
+			format, FormatText, FormatJSON, FormatYAML))
+	}
+}
+
+// writeReport encodes a report to stdout in a machine-readable format
+func writeReport(format string, report interface{}) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case FormatYAML:
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("format %q is not machine-readable", format)
+	}
+}
+
+// textOutput returns the writer for human-readable progress messages.
+// Progress is discarded in machine-readable formats so stdout only carries the report.
+func textOutput(format string) io.Writer {
+	if format == FormatText {
+		return os.Stdout
+	}
+	return io.Discard
+}
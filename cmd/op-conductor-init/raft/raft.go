@@ -23,10 +23,12 @@ func Command() *cli.Command {
 			{
 				Name:        "verify",
 				Usage:       "Verify generated Raft state",
-				Description: "Inspect and verify the contents of generated Raft state files",
+				Description: "Inspect and verify the contents of Raft state files of a single node (--state-dir) or cross-check all nodes of a cluster (--cluster-dir)",
 				Action:      VerifyAction,
 				Flags: cliapp.ProtectFlags([]cli.Flag{
-					flags.StateDirFlag,
+					flags.VerifyStateDirFlag,
+					flags.ClusterDirFlag,
+					flags.FormatFlag,
 				}),
 			},
 			{
@@ -36,6 +38,73 @@ func Command() *cli.Command {
 				Action:      InfoAction,
 				Flags: cliapp.ProtectFlags([]cli.Flag{
 					flags.StateDirFlag,
+					flags.FormatFlag,
+				}),
+			},
+			{
+				Name:        "reconfigure",
+				Usage:       "Change the membership of stopped Raft state",
+				Description: "Append a configuration entry that adds, removes, re-addresses or changes the suffrage of servers to the Raft state of every node in --cluster-dir",
+				Action:      ReconfigureAction,
+				Flags: cliapp.ProtectFlags([]cli.Flag{
+					flags.ClusterDirFlag,
+					flags.AddServersFlag,
+					flags.RemoveServersFlag,
+					flags.UpdateAddressesFlag,
+					flags.SetSuffrageFlag,
+					flags.DryRunFlag,
+					flags.FormatFlag,
+				}),
+			},
+			{
+				Name:        "expand",
+				Usage:       "Add new nodes to a stopped cluster",
+				Description: "Append a configuration entry adding new voters to the Raft state of every node in --cluster-dir and create the state of the new nodes, so all nodes can start together",
+				Action:      ExpandAction,
+				Flags: cliapp.ProtectFlags([]cli.Flag{
+					flags.ClusterDirFlag,
+					flags.AddServersFlag,
+					flags.DryRunFlag,
+					flags.FormatFlag,
+				}),
+			},
+			{
+				Name:        "migrate-addresses",
+				Usage:       "Rewrite server addresses across a stopped cluster",
+				Description: "Rewrite the server addresses in every configuration entry and snapshot of every node in --cluster-dir, keeping server IDs, indices and terms, e.g. after a namespace or DNS rename",
+				Action:      MigrateAction,
+				Flags: cliapp.ProtectFlags([]cli.Flag{
+					flags.ClusterDirFlag,
+					flags.AddressMapFlag,
+					flags.AddressRegexFlag,
+					flags.AddressReplacementFlag,
+					flags.DryRunFlag,
+					flags.FormatFlag,
+				}),
+			},
+			{
+				Name:        "recover",
+				Usage:       "Force a new configuration onto stopped Raft state after quorum loss",
+				Description: "Recover a node whose cluster lost quorum by forcing the configuration from a peers.json file onto its state, the same way HashiCorp Raft's peers.json recovery does",
+				Action:      RecoverAction,
+				Flags: cliapp.ProtectFlags([]cli.Flag{
+					flags.StateDirFlag,
+					flags.PeersFlag,
+					flags.DryRunFlag,
+					flags.FormatFlag,
+				}),
+			},
+			{
+				Name:        "install",
+				Usage:       "Install a node bundle into a state directory",
+				Description: "Verify the checksums of a bundle written by raft generate --bundle and unpack it into the state directory of a node",
+				Action:      InstallAction,
+				Flags: cliapp.ProtectFlags([]cli.Flag{
+					flags.InstallBundleFlag,
+					flags.StateDirFlag,
+					flags.InstallForceFlag,
+					flags.InstallIfEmptyFlag,
+					flags.FormatFlag,
 				}),
 			},
 			{
@@ -46,6 +115,7 @@ func Command() *cli.Command {
 				Flags: cliapp.ProtectFlags([]cli.Flag{
 					flags.StateDirFlag,
 					flags.BackupDirFlag,
+					flags.FormatFlag,
 				}),
 			},
 			{
@@ -57,6 +127,7 @@ func Command() *cli.Command {
 					flags.BackupDirFlag,
 					flags.StateDirFlag,
 					flags.RestoreForceFlag,
+					flags.FormatFlag,
 				}),
 			},
 		},
@@ -0,0 +1,398 @@
+package raft
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/raft"
+	"github.com/urfave/cli/v2"
+
+	"github.com/golem-base/op-conductor-init/pkg/config"
+	"github.com/golem-base/op-conductor-init/pkg/flags"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+// ReconfigureReport is the output of `raft reconfigure`
+type ReconfigureReport struct {
+	ClusterDirectory string           `json:"clusterDirectory" yaml:"clusterDirectory"`
+	DryRun           bool             `json:"dryRun" yaml:"dryRun"`
+	Previous         MembershipReport `json:"previous" yaml:"previous"`
+	Membership       MembershipReport `json:"membership" yaml:"membership"`
+	Term             uint64           `json:"term" yaml:"term"`
+	Changes          []string         `json:"changes" yaml:"changes"`
+	// Updated are the nodes the configuration entry is appended to
+	Updated []string `json:"updated" yaml:"updated"`
+	// Lagging are nodes behind the others, they receive the entry from the leader
+	Lagging []string `json:"lagging" yaml:"lagging"`
+}
+
+// membershipChange is the set of membership edits requested on the command line
+type membershipChange struct {
+	add       []raft.Server
+	remove    []raft.ServerID
+	addresses map[raft.ServerID]raft.ServerAddress
+	suffrage  map[raft.ServerID]raft.ServerSuffrage
+}
+
+// clusterChange is a configuration entry planned for the nodes of a stopped cluster
+type clusterChange struct {
+	clusterDir string
+	entry      *raft.Log
+	previous   raft.Configuration
+	// previousIndex is the index of the configuration the change is applied to
+	previousIndex uint64
+	next          raft.Configuration
+	// updated are the nodes the entry is appended to, lagging the nodes left to catch up
+	updated []string
+	lagging []string
+	// donor is the first updated node, whose log the lagging nodes are checked against
+	donor string
+}
+
+// ReconfigureAction handles the reconfigure subcommand
+func ReconfigureAction(ctx *cli.Context) error {
+	clusterDir := ctx.String(flags.ClusterDirFlag.Name)
+	dryRun := ctx.Bool(flags.DryRunFlag.Name)
+
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	if clusterDir == "" {
+		return exitError(ExitUsage, fmt.Errorf("--%s is required", flags.ClusterDirFlag.Name))
+	}
+
+	change, err := parseMembershipChange(ctx)
+	if err != nil {
+		return exitError(ExitUsage, err)
+	}
+
+	plan, err := planClusterChange(clusterDir, change)
+	if err != nil {
+		return err
+	}
+
+	report := &ReconfigureReport{
+		ClusterDirectory: clusterDir,
+		DryRun:           dryRun,
+		Previous:         MembershipReport{Index: plan.previousIndex, Servers: memberReports(plan.previous)},
+		Membership:       MembershipReport{Index: plan.entry.Index, Servers: memberReports(plan.next)},
+		Term:             plan.entry.Term,
+		Changes:          diffMembership(plan.previous, plan.next),
+		Updated:          plan.updated,
+		Lagging:          plan.lagging,
+	}
+
+	if !dryRun {
+		if modified, err := plan.appendEntry(); err != nil {
+			return writeError(partialFailure(modified, err))
+		}
+	}
+
+	if format != FormatText {
+		return writeReport(format, report)
+	}
+
+	fmt.Printf("Membership of %s (configuration index %d):\n", clusterDir, report.Previous.Index)
+	for _, change := range report.Changes {
+		fmt.Printf("  %s\n", change)
+	}
+	for _, name := range report.Lagging {
+		fmt.Printf("\n! %s is behind the other nodes and is left unchanged, it receives the new membership from the leader", name)
+	}
+	if len(report.Lagging) > 0 {
+		fmt.Println()
+	}
+	if dryRun {
+		fmt.Printf("\nDry run: would append configuration entry at index %d, term %d to %d node(s)\n",
+			report.Membership.Index, report.Term, len(report.Updated))
+		return nil
+	}
+	fmt.Printf("\n✓ Appended configuration entry at index %d, term %d to %d node(s)\n", report.Membership.Index, report.Term, len(report.Updated))
+	if len(report.Lagging) == 0 {
+		fmt.Println("Check the result with `raft verify --cluster-dir` before starting all nodes together")
+	}
+	return nil
+}
+
+// planClusterChange reads every node of a stopped cluster and works out the configuration entry
+// applying the change. The entry goes to the nodes whose log reaches furthest, which must agree on
+// their last entry and membership; nodes behind them would get a gap and are left to catch up
+// instead, provided their last entry is also in the donor's log. The entry uses the highest term
+// any node has recorded, so every node sees the same entry at the same index and term.
+func planClusterChange(clusterDir string, change *membershipChange) (*clusterChange, error) {
+	names, err := findNodeDirs(clusterDir)
+	if err != nil {
+		return nil, readError(err)
+	}
+
+	type position struct {
+		name          string
+		state         *nodeState
+		index, term   uint64
+		configuration raft.Configuration
+		configIndex   uint64
+	}
+	var positions []position
+	var term uint64
+	for _, name := range names {
+		state, err := readNodeState(filepath.Join(clusterDir, name))
+		if err != nil {
+			return nil, readError(fmt.Errorf("%s: %w", name, err))
+		}
+		p := position{name: name, state: state}
+		var ok bool
+		if p.configuration, p.configIndex, ok = store.LatestConfiguration(state.logs, state.snapshots); !ok {
+			return nil, exitError(ExitUnreadable, fmt.Errorf("%s: no cluster configuration found", name))
+		}
+		if p.index, p.term, err = lastEntry(state); err != nil {
+			return nil, exitError(ExitUnreadable, fmt.Errorf("%s: %w", name, err))
+		}
+		positions = append(positions, p)
+		term = max(term, state.stable.CurrentTerm, p.term)
+	}
+
+	var latest *position
+	for i := range positions {
+		if latest == nil || positions[i].index > latest.index {
+			latest = &positions[i]
+		}
+	}
+
+	plan := &clusterChange{
+		clusterDir:    clusterDir,
+		previous:      latest.configuration,
+		previousIndex: latest.configIndex,
+		updated:       []string{},
+		lagging:       []string{},
+		donor:         latest.name,
+	}
+	for _, p := range positions {
+		switch {
+		case p.index < latest.index:
+			// A donor compacted past the node's last entry cannot tell, it sends the node its snapshot
+			donorTerm, ok, err := entryTerm(latest.state, p.index)
+			if err != nil {
+				return nil, exitError(ExitUnreadable, fmt.Errorf("%s: %w", latest.name, err))
+			}
+			if ok && donorTerm != p.term {
+				return nil, exitError(ExitConflict, fmt.Errorf("%s ends at index %d in term %d, but %s has term %d at that index: their logs diverge",
+					p.name, p.index, p.term, latest.name, donorTerm))
+			}
+			plan.lagging = append(plan.lagging, p.name)
+		case p.term != latest.term || p.configIndex != latest.configIndex || !reflect.DeepEqual(p.configuration, latest.configuration):
+			return nil, exitError(ExitConflict, fmt.Errorf("%s and %s both end at index %d but disagree on the last entry or membership",
+				latest.name, p.name, p.index))
+		default:
+			plan.updated = append(plan.updated, p.name)
+		}
+	}
+
+	if plan.next, err = change.apply(latest.configuration); err != nil {
+		return nil, exitError(ExitUsage, err)
+	}
+	plan.entry = &raft.Log{
+		Index:      latest.index + 1,
+		Term:       term,
+		Type:       raft.LogConfiguration,
+		Data:       raft.EncodeConfiguration(plan.next),
+		AppendedAt: time.Now(),
+	}
+	return plan, nil
+}
+
+// appendEntry appends the entry to every up-to-date node. On failure it returns the nodes that may
+// already be modified, including the one the failure happened on.
+func (c *clusterChange) appendEntry() ([]string, error) {
+	var modified []string
+	for _, name := range c.updated {
+		modified = append(modified, name)
+		if err := store.AppendLog(filepath.Join(c.clusterDir, name), c.entry); err != nil {
+			return modified, fmt.Errorf("failed to append configuration entry to %s: %w", name, err)
+		}
+	}
+	return modified, nil
+}
+
+// parseMembershipChange reads the requested membership edits from the command line flags
+func parseMembershipChange(ctx *cli.Context) (*membershipChange, error) {
+	change := &membershipChange{
+		addresses: make(map[raft.ServerID]raft.ServerAddress),
+		suffrage:  make(map[raft.ServerID]raft.ServerSuffrage),
+	}
+	var result *multierror.Error
+
+	for _, pair := range splitList(ctx.String(flags.AddServersFlag.Name)) {
+		id, address, err := splitPair(pair)
+		if err != nil {
+			result = multierror.Append(result, fmt.Errorf("--%s: %w", flags.AddServersFlag.Name, err))
+			continue
+		}
+		change.add = append(change.add, raft.Server{ID: raft.ServerID(id), Address: raft.ServerAddress(address), Suffrage: raft.Voter})
+	}
+
+	for _, id := range splitList(ctx.String(flags.RemoveServersFlag.Name)) {
+		change.remove = append(change.remove, raft.ServerID(id))
+	}
+
+	for _, pair := range splitList(ctx.String(flags.UpdateAddressesFlag.Name)) {
+		id, address, err := splitPair(pair)
+		if err != nil {
+			result = multierror.Append(result, fmt.Errorf("--%s: %w", flags.UpdateAddressesFlag.Name, err))
+			continue
+		}
+		change.addresses[raft.ServerID(id)] = raft.ServerAddress(address)
+	}
+
+	for _, pair := range splitList(ctx.String(flags.SetSuffrageFlag.Name)) {
+		id, name, err := splitPair(pair)
+		if err == nil {
+			change.suffrage[raft.ServerID(id)], err = config.ParseSuffrage(name)
+		}
+		if err != nil {
+			result = multierror.Append(result, fmt.Errorf("--%s: %w", flags.SetSuffrageFlag.Name, err))
+		}
+	}
+
+	if result == nil && len(change.add) == 0 && len(change.remove) == 0 && len(change.addresses) == 0 && len(change.suffrage) == 0 {
+		return nil, fmt.Errorf("no membership changes requested")
+	}
+
+	return change, result.ErrorOrNil()
+}
+
+// apply returns the configuration resulting from applying the change to current.
+// All invalid edits are reported at once.
+func (c *membershipChange) apply(current raft.Configuration) (raft.Configuration, error) {
+	var result *multierror.Error
+	next := current.Clone()
+
+	find := func(id raft.ServerID) int {
+		for i, server := range next.Servers {
+			if server.ID == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for _, id := range c.remove {
+		i := find(id)
+		if i < 0 {
+			result = multierror.Append(result, fmt.Errorf("cannot remove %s: not a member", id))
+			continue
+		}
+		next.Servers = append(next.Servers[:i], next.Servers[i+1:]...)
+	}
+
+	for _, server := range c.add {
+		if find(server.ID) >= 0 {
+			result = multierror.Append(result, fmt.Errorf("cannot add %s: already a member", server.ID))
+			continue
+		}
+		next.Servers = append(next.Servers, server)
+	}
+
+	for id, address := range c.addresses {
+		i := find(id)
+		if i < 0 {
+			result = multierror.Append(result, fmt.Errorf("cannot update address of %s: not a member", id))
+			continue
+		}
+		next.Servers[i].Address = address
+	}
+
+	for id, suffrage := range c.suffrage {
+		i := find(id)
+		if i < 0 {
+			result = multierror.Append(result, fmt.Errorf("cannot set suffrage of %s: not a member", id))
+			continue
+		}
+		next.Servers[i].Suffrage = suffrage
+	}
+
+	if result != nil {
+		return raft.Configuration{}, result.ErrorOrNil()
+	}
+
+	// Existing members are only checked for collisions, so state written under older rules stays editable
+	changed := make(map[string]bool)
+	for _, server := range c.add {
+		changed[string(server.ID)] = true
+	}
+	for id := range c.addresses {
+		changed[string(id)] = true
+	}
+	nodes := make([]config.NodeConfig, 0, len(next.Servers))
+	voters := 0
+	for _, server := range next.Servers {
+		nodes = append(nodes, config.NodeConfig{ServerID: string(server.ID), Address: string(server.Address)})
+		if server.Suffrage == raft.Voter {
+			voters++
+		}
+	}
+	if err := config.ValidateChangedNodes(nodes, changed); err != nil {
+		result = multierror.Append(result, err)
+	}
+	if voters == 0 {
+		result = multierror.Append(result, fmt.Errorf("the new configuration must contain at least one voter"))
+	}
+
+	return next, result.ErrorOrNil()
+}
+
+// diffMembership describes the difference between two configurations, one line per server
+func diffMembership(previous, next raft.Configuration) []string {
+	var changes []string
+	old := make(map[raft.ServerID]raft.Server)
+	for _, server := range previous.Servers {
+		old[server.ID] = server
+	}
+
+	for _, server := range next.Servers {
+		before, ok := old[server.ID]
+		delete(old, server.ID)
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("+ %s (%s) - %s", server.ID, server.Address, server.Suffrage))
+		case before != server:
+			changes = append(changes, fmt.Sprintf("~ %s (%s) - %s -> (%s) - %s",
+				server.ID, before.Address, before.Suffrage, server.Address, server.Suffrage))
+		default:
+			changes = append(changes, fmt.Sprintf("  %s (%s) - %s", server.ID, server.Address, server.Suffrage))
+		}
+	}
+
+	for _, server := range previous.Servers {
+		if _, ok := old[server.ID]; ok {
+			changes = append(changes, fmt.Sprintf("- %s (%s) - %s", server.ID, server.Address, server.Suffrage))
+		}
+	}
+
+	return changes
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitPair splits a key=value item
+func splitPair(item string) (string, string, error) {
+	key, value, ok := strings.Cut(item, "=")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !ok || key == "" || value == "" {
+		return "", "", fmt.Errorf("invalid entry %q (expected id=value)", item)
+	}
+	return key, value, nil
+}
@@ -0,0 +1,170 @@
+package raft
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/urfave/cli/v2"
+
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+func TestReconfigureReplacesLostServer(t *testing.T) {
+	clusterDir := filepath.Join(t.TempDir(), "raft-state")
+	generateCluster(t, clusterDir, "sequencer-1")
+
+	change := &membershipChange{
+		add:       []raft.Server{{ID: "sequencer-4", Address: "sequencer-4:50050", Suffrage: raft.Voter}},
+		remove:    []raft.ServerID{"sequencer-3"},
+		addresses: map[raft.ServerID]raft.ServerAddress{"sequencer-2": "sequencer-2b:50050"},
+		suffrage:  map[raft.ServerID]raft.ServerSuffrage{},
+	}
+
+	plan, err := planClusterChange(clusterDir, change)
+	if err != nil {
+		t.Fatalf("Failed to plan membership change: %v", err)
+	}
+	if plan.entry.Index != 2 || plan.entry.Term != 1 || len(plan.updated) != 3 {
+		t.Fatalf("Expected entry at index 2, term 1 for 3 nodes, got index %d, term %d for %v", plan.entry.Index, plan.entry.Term, plan.updated)
+	}
+	if _, err := plan.appendEntry(); err != nil {
+		t.Fatalf("Failed to append configuration entry: %v", err)
+	}
+
+	report, err := verifyCluster(clusterDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sequencer-3's directory is left behind and is no longer a member
+	failed := failedChecks(t, report)
+	if len(failed) != 1 || len(failed["sequencer-3"]) != 1 || failed["sequencer-3"][0] != CheckMembership {
+		t.Fatalf("Expected only sequencer-3 to fail the membership check, failed %v", failed)
+	}
+
+	state, err := readNodeState(filepath.Join(clusterDir, "sequencer-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := formatMembers(state.report().Membership.Servers)
+	want := []string{
+		"sequencer-1 (sequencer-1:50050) - Voter",
+		"sequencer-2 (sequencer-2b:50050) - Voter",
+		"sequencer-4 (sequencer-4:50050) - Voter",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Expected membership %v, got %v", want, got)
+	}
+}
+
+func TestReconfigureUnequalLogs(t *testing.T) {
+	change := &membershipChange{remove: []raft.ServerID{"sequencer-3"}}
+	// appendNoops extends the log of a node with no-op entries in the given terms
+	appendNoops := func(t *testing.T, nodeDir string, terms ...uint64) {
+		for i, term := range terms {
+			if err := store.AppendLog(nodeDir, &raft.Log{Index: uint64(i) + 2, Term: term, Type: raft.LogNoop}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	t.Run("lagging", func(t *testing.T) {
+		clusterDir := filepath.Join(t.TempDir(), "raft-state")
+		generateCluster(t, clusterDir, "sequencer-1")
+		appendNoops(t, filepath.Join(clusterDir, "sequencer-1"), 2, 3)
+		appendNoops(t, filepath.Join(clusterDir, "sequencer-2"), 2, 3)
+		appendNoops(t, filepath.Join(clusterDir, "sequencer-3"), 2)
+
+		plan, err := planClusterChange(clusterDir, change)
+		if err != nil {
+			t.Fatalf("Failed to plan membership change: %v", err)
+		}
+		if plan.entry.Index != 4 || plan.entry.Term != 3 {
+			t.Fatalf("Expected entry at index 4, term 3, got index %d, term %d", plan.entry.Index, plan.entry.Term)
+		}
+		if len(plan.updated) != 2 || len(plan.lagging) != 1 || plan.lagging[0] != "sequencer-3" {
+			t.Fatalf("Expected sequencer-3 to lag, got updated %v, lagging %v", plan.updated, plan.lagging)
+		}
+		if _, err := plan.appendEntry(); err != nil {
+			t.Fatalf("Failed to append configuration entry: %v", err)
+		}
+
+		for _, node := range []string{"sequencer-1", "sequencer-2", "sequencer-3"} {
+			state, err := readNodeState(filepath.Join(clusterDir, node))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, index, _ := store.LatestConfiguration(state.logs, state.snapshots)
+			want := uint64(4)
+			if node == "sequencer-3" {
+				want = 1
+			}
+			if index != want {
+				t.Fatalf("%s: expected configuration at index %d, got %d", node, want, index)
+			}
+		}
+	})
+
+	t.Run("diverged", func(t *testing.T) {
+		clusterDir := filepath.Join(t.TempDir(), "raft-state")
+		generateCluster(t, clusterDir, "sequencer-1")
+		appendNoops(t, filepath.Join(clusterDir, "sequencer-1"), 3, 3)
+		appendNoops(t, filepath.Join(clusterDir, "sequencer-2"), 3, 3)
+		appendNoops(t, filepath.Join(clusterDir, "sequencer-3"), 2)
+
+		_, err := planClusterChange(clusterDir, change)
+		exit, ok := err.(cli.ExitCoder)
+		if !ok || exit.ExitCode() != ExitConflict {
+			t.Fatalf("Expected exit code %d for a diverged lagging node, got: %v", ExitConflict, err)
+		}
+	})
+}
+
+func TestReconfigureRejectsInvalidChanges(t *testing.T) {
+	current := raft.Configuration{Servers: []raft.Server{
+		{ID: "sequencer-1", Address: "sequencer-1:50050", Suffrage: raft.Voter},
+		{ID: "sequencer-2", Address: "sequencer-2:50050", Suffrage: raft.Nonvoter},
+	}}
+
+	tests := []struct {
+		name   string
+		change *membershipChange
+		errors []string
+	}{
+		{
+			name: "unknown servers",
+			change: &membershipChange{
+				remove:    []raft.ServerID{"sequencer-9"},
+				addresses: map[raft.ServerID]raft.ServerAddress{"sequencer-8": "x:1"},
+			},
+			errors: []string{"cannot remove sequencer-9", "cannot update address of sequencer-8"},
+		},
+		{
+			name: "duplicate address",
+			change: &membershipChange{
+				add: []raft.Server{{ID: "sequencer-3", Address: "sequencer-1:50050", Suffrage: raft.Voter}},
+			},
+			errors: []string{"duplicate address sequencer-1:50050"},
+		},
+		{
+			name:   "no voters left",
+			change: &membershipChange{remove: []raft.ServerID{"sequencer-1"}},
+			errors: []string{"at least one voter"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.change.apply(current)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, want := range tt.errors {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package raft
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/urfave/cli/v2"
+
+	"github.com/golem-base/op-conductor-init/pkg/flags"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+// RecoveryMetadataFile records the last recovery performed on a node directory
+const RecoveryMetadataFile = "recovery-metadata.json"
+
+// RecoverReport is the output of `raft recover`. Unless it is a dry run, it is also
+// written to recovery-metadata.json inside the state directory.
+type RecoverReport struct {
+	StateDirectory string           `json:"stateDirectory" yaml:"stateDirectory"`
+	PeersFile      string           `json:"peersFile" yaml:"peersFile"`
+	DryRun         bool             `json:"dryRun" yaml:"dryRun"`
+	RecoveredAt    *time.Time       `json:"recoveredAt,omitempty" yaml:"recoveredAt,omitempty"`
+	Previous       MembershipReport `json:"previous" yaml:"previous"`
+	Servers        []MemberReport   `json:"servers" yaml:"servers"`
+	Changes        []string         `json:"changes" yaml:"changes"`
+	PreviousTerm   uint64           `json:"previousTerm" yaml:"previousTerm"`
+	CurrentTerm    uint64           `json:"currentTerm,omitempty" yaml:"currentTerm,omitempty"`
+	Snapshot       *SnapshotReport  `json:"snapshot,omitempty" yaml:"snapshot,omitempty"`
+}
+
+// RecoverAction handles the recover subcommand
+func RecoverAction(ctx *cli.Context) error {
+	stateDir := ctx.String(flags.StateDirFlag.Name)
+	peersFile := ctx.String(flags.PeersFlag.Name)
+	dryRun := ctx.Bool(flags.DryRunFlag.Name)
+
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+
+	configuration, err := readPeers(peersFile)
+	if err != nil {
+		return err
+	}
+
+	state, err := readNodeState(stateDir)
+	if err != nil {
+		return readError(err)
+	}
+	previous, index, _ := store.LatestConfiguration(state.logs, state.snapshots)
+	// op-conductor's FSM cannot persist a snapshot without an unsafe head, so dry runs report this too
+	if store.LatestUnsafeHead(state.logs, state.snapshots) == nil {
+		return exitError(ExitUnreadable, fmt.Errorf("no unsafe payload recorded in %s, the consensus FSM cannot be snapshotted", stateDir))
+	}
+
+	report := &RecoverReport{
+		StateDirectory: stateDir,
+		PeersFile:      peersFile,
+		DryRun:         dryRun,
+		Previous:       MembershipReport{Index: index, Servers: memberReports(previous)},
+		Servers:        memberReports(configuration),
+		Changes:        diffMembership(previous, configuration),
+		PreviousTerm:   state.stable.CurrentTerm,
+	}
+
+	if !dryRun {
+		recovery, err := store.RecoverNode(stateDir, configuration)
+		if err != nil {
+			return exitError(ExitIO, err)
+		}
+		recoveredAt := time.Now().UTC()
+		report.RecoveredAt = &recoveredAt
+		report.PreviousTerm = recovery.PreviousTerm
+		report.CurrentTerm = recovery.CurrentTerm
+		report.Snapshot = &SnapshotReport{
+			ID:                 recovery.SnapshotID,
+			Index:              recovery.SnapshotIndex,
+			Term:               recovery.SnapshotTerm,
+			ConfigurationIndex: 1,
+			Members:            report.Servers,
+		}
+
+		if err := writeRecoveryMetadata(stateDir, report); err != nil {
+			return exitError(ExitIO, err)
+		}
+	}
+
+	if format != FormatText {
+		return writeReport(format, report)
+	}
+
+	fmt.Printf("Recovering Raft state in %s from %s\n", stateDir, peersFile)
+	fmt.Printf("Membership (previous configuration index %d):\n", index)
+	for _, change := range report.Changes {
+		fmt.Printf("  %s\n", change)
+	}
+	if dryRun {
+		fmt.Printf("\nDry run: would force the configuration above and raise the term above %d\n", report.PreviousTerm)
+		return nil
+	}
+	fmt.Printf("\n✓ Recovered into snapshot %s (index %d, term %d)\n", report.Snapshot.ID, report.Snapshot.Index, report.Snapshot.Term)
+	fmt.Printf("Current term raised from %d to %d\n", report.PreviousTerm, report.CurrentTerm)
+	fmt.Printf("Recovery recorded in %s\n", filepath.Join(stateDir, RecoveryMetadataFile))
+	return nil
+}
+
+// readPeers reads a peers.json file. A missing or unreadable file is a read error, while
+// malformed JSON or an invalid configuration is a usage error, like invalid membership flags.
+func readPeers(path string) (raft.Configuration, error) {
+	if _, err := os.ReadFile(path); err != nil {
+		return raft.Configuration{}, readError(fmt.Errorf("failed to read peers file: %w", err))
+	}
+	configuration, err := raft.ReadConfigJSON(path)
+	if err != nil {
+		return raft.Configuration{}, exitError(ExitUsage, fmt.Errorf("invalid peers file %s: %w", path, err))
+	}
+	return configuration, nil
+}
+
+// writeRecoveryMetadata records the recovery inside the state directory
+func writeRecoveryMetadata(stateDir string, report *RecoverReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode recovery metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(stateDir, RecoveryMetadataFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write recovery metadata: %w", err)
+	}
+	return nil
+}
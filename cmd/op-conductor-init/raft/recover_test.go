@@ -0,0 +1,44 @@
+package raft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestReadPeersClassifiesErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name string
+		path string
+		code int
+	}{
+		{"missing file", filepath.Join(dir, "missing.json"), ExitNotFound},
+		{"malformed json", write("malformed.json", "[{"), ExitUsage},
+		{"duplicate id", write("duplicate.json", `[{"id": "a", "address": "a:1"}, {"id": "a", "address": "b:1"}]`), ExitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readPeers(tt.path)
+			exit, ok := err.(cli.ExitCoder)
+			if !ok || exit.ExitCode() != tt.code {
+				t.Fatalf("Expected exit code %d, got: %v", tt.code, err)
+			}
+		})
+	}
+
+	configuration, err := readPeers(write("peers.json", `[{"id": "a", "address": "a:1"}]`))
+	if err != nil || len(configuration.Servers) != 1 {
+		t.Fatalf("Expected one server, got %+v, %v", configuration, err)
+	}
+}
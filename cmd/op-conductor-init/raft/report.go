@@ -0,0 +1,242 @@
+package raft
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/golem-base/op-conductor-init/pkg/payload"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+// StateReport describes the Raft state of a single node directory.
+// It is the output of `raft info` and part of the output of `raft verify`.
+type StateReport struct {
+	Directory   string            `json:"directory" yaml:"directory"`
+	StableStore StableStoreReport `json:"stableStore" yaml:"stableStore"`
+	LogStore    LogStoreReport    `json:"logStore" yaml:"logStore"`
+	Snapshots   []SnapshotReport  `json:"snapshots" yaml:"snapshots"`
+	Membership  *MembershipReport `json:"membership,omitempty" yaml:"membership,omitempty"`
+	UnsafeHead  *UnsafeHeadReport `json:"unsafeHead,omitempty" yaml:"unsafeHead,omitempty"`
+}
+
+// StableStoreReport describes the contents of raft-stable.db
+type StableStoreReport struct {
+	CurrentTerm       uint64 `json:"currentTerm" yaml:"currentTerm"`
+	LastVoteTerm      uint64 `json:"lastVoteTerm" yaml:"lastVoteTerm"`
+	LastVoteCandidate string `json:"lastVoteCandidate" yaml:"lastVoteCandidate"`
+	Role              string `json:"role" yaml:"role"`
+}
+
+// LogStoreReport describes the contents of raft-log.db
+type LogStoreReport struct {
+	FirstIndex uint64           `json:"firstIndex" yaml:"firstIndex"`
+	LastIndex  uint64           `json:"lastIndex" yaml:"lastIndex"`
+	Entries    []LogEntryReport `json:"entries" yaml:"entries"`
+}
+
+// LogEntryReport describes a single log entry.
+// Error is set when the entry, its configuration or its payload cannot be decoded.
+type LogEntryReport struct {
+	Index   uint64         `json:"index" yaml:"index"`
+	Term    uint64         `json:"term" yaml:"term"`
+	Type    string         `json:"type" yaml:"type"`
+	Size    int            `json:"size" yaml:"size"`
+	Members []MemberReport `json:"members,omitempty" yaml:"members,omitempty"`
+	Payload *PayloadReport `json:"payload,omitempty" yaml:"payload,omitempty"`
+	Error   string         `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// SnapshotReport describes a snapshot in the snapshots directory
+type SnapshotReport struct {
+	ID                 string         `json:"id" yaml:"id"`
+	Index              uint64         `json:"index" yaml:"index"`
+	Term               uint64         `json:"term" yaml:"term"`
+	ConfigurationIndex uint64         `json:"configurationIndex" yaml:"configurationIndex"`
+	Size               int64          `json:"size" yaml:"size"`
+	Members            []MemberReport `json:"members,omitempty" yaml:"members,omitempty"`
+	Error              string         `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// MembershipReport describes the latest cluster membership and the index it was recorded at
+type MembershipReport struct {
+	Index   uint64         `json:"index" yaml:"index"`
+	Servers []MemberReport `json:"servers" yaml:"servers"`
+}
+
+// MemberReport describes a single cluster member
+type MemberReport struct {
+	ID       string `json:"id" yaml:"id"`
+	Address  string `json:"address" yaml:"address"`
+	Suffrage string `json:"suffrage" yaml:"suffrage"`
+}
+
+// PayloadReport describes an unsafe execution payload
+type PayloadReport struct {
+	BlockNumber      uint64 `json:"blockNumber" yaml:"blockNumber"`
+	BlockHash        string `json:"blockHash" yaml:"blockHash"`
+	ParentHash       string `json:"parentHash" yaml:"parentHash"`
+	Timestamp        uint64 `json:"timestamp" yaml:"timestamp"`
+	TransactionCount int    `json:"transactionCount" yaml:"transactionCount"`
+	ParentBeaconRoot string `json:"parentBeaconRoot,omitempty" yaml:"parentBeaconRoot,omitempty"`
+}
+
+// UnsafeHeadReport describes the latest unsafe head recorded on disk and where it was found
+type UnsafeHeadReport struct {
+	PayloadReport  `yaml:",inline"`
+	SourceIndex    uint64 `json:"sourceIndex" yaml:"sourceIndex"`
+	SourceSnapshot string `json:"sourceSnapshot,omitempty" yaml:"sourceSnapshot,omitempty"`
+}
+
+// unreadableEntryType is reported as the type of log entries that cannot be read
+const unreadableEntryType = "Unreadable"
+
+// nodeState is the raw Raft state read from a node directory
+type nodeState struct {
+	dir       string
+	stable    *store.StableState
+	logs      *store.LogState
+	snapshots []*store.Snapshot
+}
+
+// readNodeState reads the stable store, log store and snapshots of a node directory
+func readNodeState(stateDir string) (*nodeState, error) {
+	stable, err := store.ReadStableStore(filepath.Join(stateDir, store.StableStoreFile))
+	if err != nil {
+		return nil, fmt.Errorf("error reading stable store: %w", err)
+	}
+
+	logs, err := store.ReadLogStore(filepath.Join(stateDir, store.LogStoreFile))
+	if err != nil {
+		return nil, fmt.Errorf("error reading log store: %w", err)
+	}
+
+	snapshots, err := store.ReadSnapshots(stateDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshots: %w", err)
+	}
+
+	return &nodeState{dir: stateDir, stable: stable, logs: logs, snapshots: snapshots}, nil
+}
+
+// report builds the machine-readable description of the node state
+func (s *nodeState) report() *StateReport {
+	report := &StateReport{
+		Directory: s.dir,
+		StableStore: StableStoreReport{
+			CurrentTerm:       s.stable.CurrentTerm,
+			LastVoteTerm:      s.stable.LastVoteTerm,
+			LastVoteCandidate: s.stable.LastVoteCand,
+			Role:              determineRole(s.stable.LastVoteCand, filepath.Base(filepath.Clean(s.dir))),
+		},
+		LogStore: LogStoreReport{
+			FirstIndex: s.logs.FirstIndex,
+			LastIndex:  s.logs.LastIndex,
+			Entries:    []LogEntryReport{},
+		},
+		Snapshots: []SnapshotReport{},
+	}
+
+	for _, entry := range s.logs.Entries {
+		report.LogStore.Entries = append(report.LogStore.Entries, logEntryReport(entry))
+	}
+
+	for _, snapshot := range s.snapshots {
+		snapshotReport := SnapshotReport{ID: snapshot.ID}
+		if snapshot.Err != nil {
+			snapshotReport.Error = snapshot.Err.Error()
+		} else {
+			snapshotReport.Index = snapshot.Index
+			snapshotReport.Term = snapshot.Term
+			snapshotReport.ConfigurationIndex = snapshot.ConfigurationIndex
+			snapshotReport.Size = snapshot.Size
+			snapshotReport.Members = memberReports(snapshot.Configuration)
+		}
+		report.Snapshots = append(report.Snapshots, snapshotReport)
+	}
+
+	if configuration, index, ok := store.LatestConfiguration(s.logs, s.snapshots); ok {
+		report.Membership = &MembershipReport{Index: index, Servers: memberReports(configuration)}
+	}
+
+	if head := store.LatestUnsafeHead(s.logs, s.snapshots); head != nil {
+		report.UnsafeHead = &UnsafeHeadReport{
+			PayloadReport:  payloadReport(head.Envelope),
+			SourceIndex:    head.Index,
+			SourceSnapshot: head.Snapshot,
+		}
+	}
+
+	return report
+}
+
+func logEntryReport(entry store.LogEntry) LogEntryReport {
+	report := LogEntryReport{Index: entry.Index}
+	if entry.Err != nil {
+		report.Type = unreadableEntryType
+		report.Error = entry.Err.Error()
+		return report
+	}
+
+	log := entry.Log
+	report.Term = log.Term
+	report.Type = getLogTypeName(log.Type)
+	report.Size = len(log.Data)
+
+	switch log.Type {
+	case raft.LogConfiguration:
+		configuration, err := store.DecodeConfiguration(log.Data)
+		if err != nil {
+			report.Error = fmt.Sprintf("undecodable configuration: %v", err)
+			break
+		}
+		report.Members = memberReports(configuration)
+	case raft.LogCommand:
+		// Command entries carry unsafe payloads committed through op-conductor's FSM
+		envelope, err := payload.Decode(log.Data)
+		if err != nil {
+			report.Error = fmt.Sprintf("undecodable unsafe payload: %v", err)
+			break
+		}
+		decoded := payloadReport(envelope)
+		report.Payload = &decoded
+	case raft.LogNoop, raft.LogBarrier, raft.LogAddPeerDeprecated, raft.LogRemovePeerDeprecated:
+	default:
+		report.Error = fmt.Sprintf("unknown entry type %d", log.Type)
+	}
+
+	if report.Error == "" && log.Index != entry.Index {
+		report.Error = fmt.Sprintf("entry records index %d", log.Index)
+	}
+
+	return report
+}
+
+func memberReports(configuration raft.Configuration) []MemberReport {
+	members := make([]MemberReport, 0, len(configuration.Servers))
+	for _, server := range configuration.Servers {
+		members = append(members, MemberReport{
+			ID:       string(server.ID),
+			Address:  string(server.Address),
+			Suffrage: server.Suffrage.String(),
+		})
+	}
+	return members
+}
+
+func payloadReport(envelope *eth.ExecutionPayloadEnvelope) PayloadReport {
+	execPayload := envelope.ExecutionPayload
+	report := PayloadReport{
+		BlockNumber:      uint64(execPayload.BlockNumber),
+		BlockHash:        execPayload.BlockHash.String(),
+		ParentHash:       execPayload.ParentHash.String(),
+		Timestamp:        uint64(execPayload.Timestamp),
+		TransactionCount: len(execPayload.Transactions),
+	}
+	if envelope.ParentBeaconBlockRoot != nil {
+		report.ParentBeaconRoot = envelope.ParentBeaconBlockRoot.String()
+	}
+	return report
+}
@@ -5,24 +5,50 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/urfave/cli/v2"
+
+	"github.com/golem-base/op-conductor-init/pkg/store"
 )
 
+// RestoreReport is the output of `raft restore`
+type RestoreReport struct {
+	BackupDirectory string          `json:"backupDirectory" yaml:"backupDirectory"`
+	StateDirectory  string          `json:"stateDirectory" yaml:"stateDirectory"`
+	Manifest        *BackupManifest `json:"manifest,omitempty" yaml:"manifest,omitempty"`
+	Files           []string        `json:"files" yaml:"files"`
+	Overwritten     []string        `json:"overwritten" yaml:"overwritten"`
+}
+
 // RestoreAction handles the restore subcommand
 func RestoreAction(ctx *cli.Context) error {
 	backupDir := ctx.String("backup-dir")
 	stateDir := ctx.String("state-dir")
 	force := ctx.Bool("force")
 
-	fmt.Printf("Restoring Raft state from backup...\n")
-	fmt.Printf("Source: %s\n", backupDir)
-	fmt.Printf("Destination: %s\n", stateDir)
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+	out := textOutput(format)
+
+	fmt.Fprintf(out, "Restoring Raft state from backup...\n")
+	fmt.Fprintf(out, "Source: %s\n", backupDir)
+	fmt.Fprintf(out, "Destination: %s\n", stateDir)
 
 	// Check if backup directory exists
 	if _, err := os.Stat(backupDir); os.IsNotExist(err) {
-		return fmt.Errorf("backup directory does not exist: %s", backupDir)
+		return exitError(ExitNotFound, fmt.Errorf("backup directory does not exist: %s", backupDir))
+	}
+
+	report := &RestoreReport{
+		BackupDirectory: backupDir,
+		StateDirectory:  stateDir,
+		Files:           []string{},
+		Overwritten:     []string{},
 	}
 
 	// Check for metadata file
@@ -31,69 +57,88 @@ func RestoreAction(ctx *cli.Context) error {
 		// Read and display metadata
 		content, err := os.ReadFile(metadataPath)
 		if err == nil {
-			fmt.Printf("\nBackup metadata:\n")
-			fmt.Printf("%s\n", string(content))
+			fmt.Fprintf(out, "\nBackup metadata:\n")
+			fmt.Fprintf(out, "%s\n", string(content))
 		}
 	}
+	if report.Manifest, err = readBackupManifest(backupDir); err != nil {
+		return exitError(ExitUnreadable, err)
+	}
 
-	// Find all .db files in backup directory
+	// Find all state files in backup directory
 	var filesToRestore []string
-	err := filepath.Walk(backupDir, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(backupDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && filepath.Ext(path) == ".db" {
+		if !info.IsDir() && isStateFile(backupDir, path) {
 			filesToRestore = append(filesToRestore, path)
 		}
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("failed to scan backup directory: %w", err)
+		return readError(fmt.Errorf("failed to scan backup directory: %w", err))
 	}
 
 	if len(filesToRestore) == 0 {
-		return fmt.Errorf("no backup files found in %s", backupDir)
+		return exitError(ExitNotFound, fmt.Errorf("no backup files found in %s", backupDir))
 	}
 
-	// Check for existing files if not forcing
-	if !force {
-		var existingFiles []string
-		for _, srcPath := range filesToRestore {
-			relPath, err := filepath.Rel(backupDir, srcPath)
-			if err != nil {
-				continue
-			}
-			dstPath := filepath.Join(stateDir, relPath)
-			if _, err := os.Stat(dstPath); err == nil {
-				existingFiles = append(existingFiles, dstPath)
-			}
+	// Check for existing files
+	var relPaths []string
+	for _, srcPath := range filesToRestore {
+		relPath, err := filepath.Rel(backupDir, srcPath)
+		if err != nil {
+			continue
+		}
+		relPaths = append(relPaths, relPath)
+		dstPath := filepath.Join(stateDir, relPath)
+		if _, err := os.Stat(dstPath); err == nil {
+			report.Overwritten = append(report.Overwritten, dstPath)
 		}
+	}
+	stale := staleSnapshots(stateDir, relPaths)
+	for _, relPath := range stale {
+		report.Overwritten = append(report.Overwritten, filepath.Join(stateDir, relPath))
+	}
 
-		if len(existingFiles) > 0 {
-			fmt.Printf("\nWarning: The following files will be overwritten:\n")
-			for _, file := range existingFiles {
-				fmt.Printf("  - %s\n", file)
-			}
+	if !force && len(report.Overwritten) > 0 {
+		// Machine-readable runs are unattended, so refuse instead of prompting
+		if format != FormatText {
+			return exitError(ExitConflict, fmt.Errorf("restore would overwrite %d existing file(s), use --force to overwrite", len(report.Overwritten)))
+		}
 
-			if !promptConfirmation("\nContinue with restore?") {
-				fmt.Println("Restore cancelled.")
-				return nil
-			}
+		fmt.Printf("\nWarning: The following files will be overwritten:\n")
+		for _, file := range report.Overwritten {
+			fmt.Printf("  - %s\n", file)
+		}
+
+		if !promptConfirmation("\nContinue with restore?") {
+			fmt.Println("Restore cancelled.")
+			return nil
 		}
 	}
 
 	// Create state directory if it doesn't exist
 	if err := os.MkdirAll(stateDir, 0o755); err != nil {
-		return fmt.Errorf("failed to create state directory: %w", err)
+		return exitError(ExitIO, fmt.Errorf("failed to create state directory: %w", err))
+	}
+
+	// Remove snapshots the backup does not contain, a newer one would win over the restored state
+	for _, relPath := range stale {
+		if err := os.RemoveAll(filepath.Join(stateDir, relPath)); err != nil {
+			return exitError(ExitIO, fmt.Errorf("failed to remove snapshot %s: %w", relPath, err))
+		}
+		fmt.Fprintf(out, "  ✗ %s\n", relPath)
 	}
 
 	// Restore each file
-	fmt.Printf("\nRestoring %d files:\n", len(filesToRestore))
+	fmt.Fprintf(out, "\nRestoring %d files:\n", len(filesToRestore))
 	for _, srcPath := range filesToRestore {
 		// Calculate relative path from backup directory
 		relPath, err := filepath.Rel(backupDir, srcPath)
 		if err != nil {
-			return fmt.Errorf("failed to calculate relative path: %w", err)
+			return exitError(ExitFailure, fmt.Errorf("failed to calculate relative path: %w", err))
 		}
 
 		// Skip metadata file
@@ -107,15 +152,20 @@ func RestoreAction(ctx *cli.Context) error {
 		// Create destination directory if needed
 		dstDir := filepath.Dir(dstPath)
 		if err := os.MkdirAll(dstDir, 0o755); err != nil {
-			return fmt.Errorf("failed to create destination directory: %w", err)
+			return exitError(ExitIO, fmt.Errorf("failed to create destination directory: %w", err))
 		}
 
 		// Copy file
-		if err := copyFile(srcPath, dstPath); err != nil {
-			return fmt.Errorf("failed to restore %s: %w", relPath, err)
+		if _, err := copyFile(srcPath, dstPath); err != nil {
+			return exitError(ExitIO, fmt.Errorf("failed to restore %s: %w", relPath, err))
 		}
+		report.Files = append(report.Files, relPath)
+
+		fmt.Fprintf(out, "  ✓ %s\n", relPath)
+	}
 
-		fmt.Printf("  ✓ %s\n", relPath)
+	if format != FormatText {
+		return writeReport(format, report)
 	}
 
 	fmt.Printf("\n✓ Restore completed successfully\n")
@@ -124,6 +174,38 @@ func RestoreAction(ctx *cli.Context) error {
 	return nil
 }
 
+// staleSnapshots returns the snapshots in the snapshot directories of stateDir that restoring the
+// files at relPaths does not bring back
+func staleSnapshots(stateDir string, relPaths []string) []string {
+	restored := make(map[string]bool)
+	roots := make(map[string]bool)
+	for _, relPath := range relPaths {
+		dirs := strings.Split(filepath.Dir(relPath), string(filepath.Separator))
+		i := slices.Index(dirs, store.SnapshotsDir)
+		if i < 0 || i+1 >= len(dirs) {
+			continue
+		}
+		root := filepath.Join(dirs[:i+1]...)
+		roots[root] = true
+		restored[filepath.Join(root, dirs[i+1])] = true
+	}
+
+	var stale []string
+	for root := range roots {
+		entries, err := os.ReadDir(filepath.Join(stateDir, root))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if relPath := filepath.Join(root, entry.Name()); !restored[relPath] {
+				stale = append(stale, relPath)
+			}
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
 // promptConfirmation asks the user for yes/no confirmation
 func promptConfirmation(prompt string) bool {
 	reader := bufio.NewReader(os.Stdin)
@@ -4,36 +4,167 @@ import (
 	"encoding/binary"
 	"fmt"
 	"path/filepath"
+	"time"
 
-	"github.com/hashicorp/raft"
 	"github.com/urfave/cli/v2"
 	bolt "go.etcd.io/bbolt"
+
+	"github.com/golem-base/op-conductor-init/pkg/store"
 )
 
+// VerifyReport is the output of `raft verify`: the node state and the problems found in it
+type VerifyReport struct {
+	*StateReport `yaml:",inline"`
+	Valid        bool     `json:"valid" yaml:"valid"`
+	Problems     []string `json:"problems" yaml:"problems"`
+}
+
 // VerifyAction handles the verify subcommand
 func VerifyAction(ctx *cli.Context) error {
 	stateDir := ctx.String("state-dir")
+	clusterDir := ctx.String("cluster-dir")
+
+	format, err := outputFormat(ctx)
+	if err != nil {
+		return err
+	}
+
+	if (stateDir == "") == (clusterDir == "") {
+		return exitError(ExitUsage, fmt.Errorf("exactly one of --state-dir or --cluster-dir must be set"))
+	}
+	if clusterDir != "" {
+		return verifyClusterAction(format, clusterDir)
+	}
+
+	state, err := readNodeState(stateDir)
+	if err != nil {
+		return readError(err)
+	}
+	report := &VerifyReport{StateReport: state.report()}
+	report.Problems = verifyState(report.StateReport)
+	report.Valid = len(report.Problems) == 0
+
+	if format != FormatText {
+		if err := writeReport(format, report); err != nil {
+			return exitError(ExitIO, fmt.Errorf("failed to write report: %w", err))
+		}
+	} else if err := printVerifyReport(report); err != nil {
+		return readError(err)
+	}
+
+	if !report.Valid {
+		return exitError(ExitVerifyFailed, fmt.Errorf("raft state verification failed with %d problem(s)", len(report.Problems)))
+	}
+	return nil
+}
 
-	fmt.Printf("Verifying Raft state in: %s\n\n", stateDir)
+// verifyClusterAction cross-checks all node directories of a cluster
+func verifyClusterAction(format, clusterDir string) error {
+	report, err := verifyCluster(clusterDir)
+	if err != nil {
+		return readError(err)
+	}
+
+	if format != FormatText {
+		if err := writeReport(format, report); err != nil {
+			return exitError(ExitIO, fmt.Errorf("failed to write report: %w", err))
+		}
+	} else {
+		printClusterVerifyReport(report)
+	}
+
+	if !report.Valid {
+		return exitError(ExitVerifyFailed, fmt.Errorf("raft cluster verification failed with %d problem(s)", len(report.Problems)))
+	}
+	return nil
+}
+
+// verifyState returns the problems found in a node state report
+func verifyState(report *StateReport) []string {
+	problems := []string{}
+
+	var prevTerm uint64
+	for _, entry := range report.LogStore.Entries {
+		if entry.Error != "" {
+			problems = append(problems, fmt.Sprintf("entry %d: %s", entry.Index, entry.Error))
+		}
+		if entry.Type == unreadableEntryType {
+			continue
+		}
+		if entry.Term < prevTerm {
+			problems = append(problems, fmt.Sprintf("entry %d has term %d lower than previous term %d", entry.Index, entry.Term, prevTerm))
+		}
+		prevTerm = entry.Term
+	}
+
+	for _, snapshot := range report.Snapshots {
+		if snapshot.Error != "" {
+			problems = append(problems, fmt.Sprintf("snapshot %s has unreadable metadata: %s", snapshot.ID, snapshot.Error))
+		}
+	}
+
+	if report.Membership == nil {
+		problems = append(problems, "no cluster configuration found in log store or snapshots")
+	}
+
+	return problems
+}
+
+// printVerifyReport prints the verify report in the human-readable text format
+func printVerifyReport(report *VerifyReport) error {
+	fmt.Printf("Verifying Raft state in: %s\n\n", report.Directory)
 
-	// Check stable store
 	fmt.Println("=== Stable Store (raft-stable.db) ===")
-	stablePath := filepath.Join(stateDir, "raft-stable.db")
-	if err := verifyStableStore(stablePath); err != nil {
+	if err := verifyStableStore(filepath.Join(report.Directory, store.StableStoreFile)); err != nil {
 		return fmt.Errorf("error verifying stable store: %w", err)
 	}
 
 	fmt.Println("\n=== Log Store (raft-log.db) ===")
-	logPath := filepath.Join(stateDir, "raft-log.db")
-	if err := verifyLogStore(logPath); err != nil {
-		return fmt.Errorf("error verifying log store: %w", err)
+	fmt.Printf("  FirstIndex: %d\n", report.LogStore.FirstIndex)
+	fmt.Printf("  LastIndex: %d\n", report.LogStore.LastIndex)
+	fmt.Println("  Entries:")
+	for _, entry := range report.LogStore.Entries {
+		fmt.Printf("    Entry %d: term=%d, type=%s, data_len=%d\n", entry.Index, entry.Term, entry.Type, entry.Size)
+		for _, member := range entry.Members {
+			fmt.Printf("      Server %s: address=%s, suffrage=%s\n", member.ID, member.Address, member.Suffrage)
+		}
+		if entry.Error != "" {
+			fmt.Printf("      ERROR: %s\n", entry.Error)
+		}
+	}
+	if len(report.LogStore.Entries) == 0 {
+		fmt.Println("    (no entries)")
+	}
+
+	if len(report.Snapshots) > 0 {
+		fmt.Println("\n=== Snapshots (snapshots/) ===")
+		for _, snapshot := range report.Snapshots {
+			if snapshot.Error != "" {
+				fmt.Printf("  Snapshot %s: unreadable metadata: %s\n", snapshot.ID, snapshot.Error)
+				continue
+			}
+			fmt.Printf("  Snapshot %s: index=%d, term=%d, configuration_index=%d, size=%d\n",
+				snapshot.ID, snapshot.Index, snapshot.Term, snapshot.ConfigurationIndex, snapshot.Size)
+			for _, member := range snapshot.Members {
+				fmt.Printf("      Server %s: address=%s, suffrage=%s\n", member.ID, member.Address, member.Suffrage)
+			}
+		}
 	}
 
+	if !report.Valid {
+		fmt.Printf("\n✗ Found %d problem(s):\n", len(report.Problems))
+		for _, problem := range report.Problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		return nil
+	}
+
+	fmt.Println("\n✓ Raft state is consistent")
 	return nil
 }
 
 func verifyStableStore(path string) error {
-	db, err := bolt.Open(path, 0o600, nil)
+	db, err := bolt.Open(path, 0o600, &bolt.Options{ReadOnly: true, Timeout: time.Second})
 	if err != nil {
 		return fmt.Errorf("failed to open stable store: %w", err)
 	}
@@ -65,62 +196,3 @@ func verifyStableStore(path string) error {
 		})
 	})
 }
-
-func verifyLogStore(path string) error {
-	db, err := bolt.Open(path, 0o600, nil)
-	if err != nil {
-		return fmt.Errorf("failed to open log store: %w", err)
-	}
-	defer db.Close()
-
-	return db.View(func(tx *bolt.Tx) error {
-		// Check conf bucket
-		confBucket := tx.Bucket([]byte("conf"))
-		if confBucket != nil {
-			fmt.Println("  Conf bucket:")
-			confBucket.ForEach(func(k, v []byte) error {
-				if string(k) == "FirstIndex" || string(k) == "LastIndex" {
-					if len(v) == 8 {
-						fmt.Printf("    %s: %d\n", k, binary.BigEndian.Uint64(v))
-					}
-				}
-				return nil
-			})
-		}
-
-		// Check logs bucket
-		logsBucket := tx.Bucket([]byte("logs"))
-		if logsBucket == nil {
-			return fmt.Errorf("logs bucket not found")
-		}
-
-		fmt.Println("  Logs bucket:")
-		count := 0
-		return logsBucket.ForEach(func(k, v []byte) error {
-			if len(k) == 8 && len(v) >= 17 {
-				index := binary.BigEndian.Uint64(k)
-				term := binary.BigEndian.Uint64(v[0:8])
-				logIndex := binary.BigEndian.Uint64(v[8:16])
-				logType := v[16]
-				dataLen := len(v) - 17
-
-				fmt.Printf("    Entry %d: term=%d, index=%d, type=%d, data_len=%d\n",
-					index, term, logIndex, logType, dataLen)
-
-				// If it's a configuration entry, try to decode it
-				if logType == uint8(raft.LogConfiguration) {
-					fmt.Printf("      Configuration data (first 50 bytes): %x\n", v[17:min(67, len(v))])
-				}
-			}
-			count++
-			return nil
-		})
-	})
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
@@ -0,0 +1,186 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golem-base/op-conductor-init/pkg/config"
+)
+
+// Bundle formats
+const (
+	// FormatTarGz is a gzip-compressed tarball of the node's state and manifest
+	FormatTarGz = config.BundleTarGz
+	// FormatOCI is an OCI image layout tarball with the tar.gz bundle as its only layer,
+	// so it can be pushed to and pulled from any OCI registry
+	FormatOCI = config.BundleOCI
+)
+
+// ManifestFile is the manifest at the root of every bundle
+const ManifestFile = "bundle-manifest.json"
+
+// ManifestVersion is the version of the manifest schema written by this tool
+const ManifestVersion = 1
+
+// Manifest describes the state of a single node in a bundle
+type Manifest struct {
+	Version     int       `json:"version" yaml:"version"`
+	GeneratedAt time.Time `json:"generatedAt" yaml:"generatedAt"`
+
+	ServerID      string `json:"serverId" yaml:"serverId"`
+	Address       string `json:"address" yaml:"address"`
+	Suffrage      string `json:"suffrage" yaml:"suffrage"`
+	Network       string `json:"network,omitempty" yaml:"network,omitempty"`
+	InitialLeader string `json:"initialLeader" yaml:"initialLeader"`
+	InitialTerm   uint64 `json:"initialTerm" yaml:"initialTerm"`
+	Mode          string `json:"mode" yaml:"mode"`
+
+	Files []File `json:"files" yaml:"files"`
+	// ConductorFlags are the recommended op-conductor flags, with --raft.storage.dir only when it was configured
+	ConductorFlags []config.ConductorFlag `json:"conductorFlags" yaml:"conductorFlags"`
+}
+
+// File is a single state file in a bundle, relative to the node's state directory
+type File struct {
+	Path   string `json:"path" yaml:"path"`
+	Size   int64  `json:"size" yaml:"size"`
+	SHA256 string `json:"sha256" yaml:"sha256"`
+}
+
+// FileName returns the archive name of a node's bundle in the given format
+func FileName(serverID, format string) string {
+	if format == FormatOCI {
+		return serverID + ".oci.tar"
+	}
+	return serverID + ".tar.gz"
+}
+
+// Write creates the bundle of the state in nodeDir at path.
+// The files of the manifest are filled in from nodeDir.
+func Write(path, format, nodeDir string, manifest *Manifest) error {
+	files, err := collectFiles(nodeDir)
+	if err != nil {
+		return err
+	}
+	manifest.Version = ManifestVersion
+	manifest.Files = files
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer f.Close()
+
+	switch format {
+	case FormatTarGz:
+		err = writePayload(f, nodeDir, manifest)
+	case FormatOCI:
+		err = writeOCI(f, nodeDir, manifest)
+	default:
+		err = fmt.Errorf("unknown bundle format %q (expected %s or %s)", format, FormatTarGz, FormatOCI)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync bundle: %w", err)
+	}
+	return f.Close()
+}
+
+// collectFiles lists the files below nodeDir with their size and SHA-256 checksum
+func collectFiles(nodeDir string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(nodeDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(nodeDir, path)
+		if err != nil {
+			return err
+		}
+		sum, size, err := checksumFile(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, File{Path: filepath.ToSlash(rel), Size: size, SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect state files: %w", err)
+	}
+	return files, nil
+}
+
+func checksumFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// writePayload writes the tar.gz bundle: the manifest followed by the files it lists
+func writePayload(w io.Writer, nodeDir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle manifest: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	modTime := manifest.GeneratedAt
+	if err := tw.WriteHeader(&tar.Header{Name: ManifestFile, Mode: 0o644, Size: int64(len(data)), ModTime: modTime}); err != nil {
+		return fmt.Errorf("failed to write bundle manifest: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write bundle manifest: %w", err)
+	}
+
+	for _, file := range manifest.Files {
+		if err := writeTarFile(tw, filepath.Join(nodeDir, filepath.FromSlash(file.Path)), file, modTime); err != nil {
+			return fmt.Errorf("failed to add %s to bundle: %w", file.Path, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	return nil
+}
+
+func writeTarFile(tw *tar.Writer, path string, file File, modTime time.Time) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := tw.WriteHeader(&tar.Header{Name: file.Path, Mode: 0o644, Size: file.Size, ModTime: modTime}); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, f, file.Size)
+	return err
+}
@@ -0,0 +1,154 @@
+package bundle
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+func testNodeDir(t *testing.T) string {
+	t.Helper()
+	nodeDir := filepath.Join(t.TempDir(), "sequencer-1")
+	if err := os.Mkdir(nodeDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	configuration := raft.Configuration{
+		Servers: []raft.Server{{Suffrage: raft.Voter, ID: "sequencer-1", Address: "sequencer-1:50050"}},
+	}
+	if err := store.CreateStableStore(nodeDir, "sequencer-1", 1, true); err != nil {
+		t.Fatal(err)
+	}
+	entry := &raft.Log{Index: 1, Term: 1, Type: raft.LogConfiguration, Data: raft.EncodeConfiguration(configuration)}
+	if err := store.CreateLogStore(nodeDir, entry); err != nil {
+		t.Fatal(err)
+	}
+	return nodeDir
+}
+
+func testManifest() *Manifest {
+	return &Manifest{
+		GeneratedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		ServerID:      "sequencer-1",
+		Address:       "sequencer-1:50050",
+		Suffrage:      "Voter",
+		InitialLeader: "sequencer-1",
+		InitialTerm:   1,
+		Mode:          "log",
+	}
+}
+
+func TestInstall(t *testing.T) {
+	for _, format := range []string{FormatTarGz, FormatOCI} {
+		t.Run(format, func(t *testing.T) {
+			nodeDir := testNodeDir(t)
+			bundlePath := filepath.Join(t.TempDir(), FileName("sequencer-1", format))
+			if err := Write(bundlePath, format, nodeDir, testManifest()); err != nil {
+				t.Fatalf("Failed to write bundle: %v", err)
+			}
+
+			stateDir := filepath.Join(t.TempDir(), "raft")
+			result, err := Install(bundlePath, stateDir, false)
+			if err != nil {
+				t.Fatalf("Failed to install bundle: %v", err)
+			}
+			if result.AlreadyInstalled || len(result.Installed) != 2 || result.Manifest.ServerID != "sequencer-1" {
+				t.Fatalf("Unexpected install result: %+v", result)
+			}
+			for _, name := range []string{store.StableStoreFile, store.LogStoreFile} {
+				want, err := os.ReadFile(filepath.Join(nodeDir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := os.ReadFile(filepath.Join(stateDir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("Installed %s differs from the generated file", name)
+				}
+			}
+
+			// Installing again is a no-op
+			if result, err = Install(bundlePath, stateDir, false); err != nil || !result.AlreadyInstalled {
+				t.Fatalf("Expected repeated install to be a no-op, got %+v (err: %v)", result, err)
+			}
+
+			// Different state is only replaced with force
+			if err := os.WriteFile(filepath.Join(stateDir, store.LogStoreFile), []byte("changed"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Install(bundlePath, stateDir, false); !errors.Is(err, ErrConflict) {
+				t.Fatalf("Expected conflict with different state, got: %v", err)
+			}
+			if result, err = Install(bundlePath, stateDir, true); err != nil || result.AlreadyInstalled {
+				t.Fatalf("Expected forced install to replace the state, got %+v (err: %v)", result, err)
+			}
+
+			if entries, _ := os.ReadDir(stateDir); len(entries) != 2 {
+				t.Fatalf("Expected only the state files in the state directory, got %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestInstallAfterInterruptedInstall(t *testing.T) {
+	nodeDir := testNodeDir(t)
+	bundlePath := filepath.Join(t.TempDir(), FileName("sequencer-1", FormatTarGz))
+	if err := Write(bundlePath, FormatTarGz, nodeDir, testManifest()); err != nil {
+		t.Fatal(err)
+	}
+
+	// An install interrupted before the stable store was moved into place left only a log store
+	stateDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(stateDir, store.LogStoreFile), []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if existing := ExistingState(stateDir); len(existing) != 0 {
+		t.Fatalf("Expected a directory without a stable store to hold no state, got %v", existing)
+	}
+
+	if _, err := Install(bundlePath, stateDir, false); err != nil {
+		t.Fatalf("Expected the interrupted install to be completed without --force, got: %v", err)
+	}
+	if existing := ExistingState(stateDir); len(existing) != 2 {
+		t.Fatalf("Expected the installed state files, got %v", existing)
+	}
+}
+
+func TestInstallChecksumMismatch(t *testing.T) {
+	nodeDir := testNodeDir(t)
+	manifest := testManifest()
+	manifest.Version = ManifestVersion
+
+	files, err := collectFiles(nodeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files[0].SHA256 = "00" + files[0].SHA256[2:]
+	manifest.Files = files
+
+	var buf bytes.Buffer
+	if err := writePayload(&buf, nodeDir, manifest); err != nil {
+		t.Fatal(err)
+	}
+	bundlePath := filepath.Join(t.TempDir(), "sequencer-1.tar.gz")
+	if err := os.WriteFile(bundlePath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stateDir := t.TempDir()
+	if _, err := Install(bundlePath, stateDir, false); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Expected checksum mismatch, got: %v", err)
+	}
+	if entries, _ := os.ReadDir(stateDir); len(entries) != 0 {
+		t.Fatalf("Expected nothing to be installed, got %d entries", len(entries))
+	}
+}
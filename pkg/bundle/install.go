@@ -0,0 +1,282 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+var (
+	// ErrInvalidBundle is returned for archives that are not well-formed bundles
+	ErrInvalidBundle = errors.New("invalid bundle")
+	// ErrChecksumMismatch is returned when a file does not match the checksum in its manifest
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrConflict is returned when the state directory holds different state and force is not set
+	ErrConflict = errors.New("state directory contains different state")
+)
+
+// InstallResult describes the outcome of Install
+type InstallResult struct {
+	Manifest *Manifest
+	// Installed lists the files written to the state directory
+	Installed []string
+	// AlreadyInstalled is set when the state directory already held exactly the bundled state
+	AlreadyInstalled bool
+}
+
+// Install verifies the checksums of a bundle and unpacks it into stateDir.
+// Installing the same bundle again is a no-op. Different existing state is only replaced with force.
+// The stable store is removed first and moved into place last, so a directory holding a stable store
+// always holds complete state, and an interrupted install is not mistaken for an installed one.
+func Install(bundlePath, stateDir string, force bool) (*InstallResult, error) {
+	payload, err := openPayload(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	defer payload.Close()
+
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	// Unpack next to the final location, so the files can be renamed into place once all are verified
+	stagingDir, err := os.MkdirTemp(stateDir, ".install-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	manifest, err := extractPayload(payload, stagingDir)
+	if err != nil {
+		return nil, err
+	}
+	result := &InstallResult{Manifest: manifest, Installed: []string{}}
+
+	installed, existing, err := compareState(stateDir, manifest)
+	if err != nil {
+		return nil, err
+	}
+	if installed {
+		result.AlreadyInstalled = true
+		return result, nil
+	}
+	if len(existing) > 0 && !force {
+		return nil, fmt.Errorf("%w (%s), use --force to replace it", ErrConflict, strings.Join(existing, ", "))
+	}
+
+	// Remove the previous state entirely, so no stale log or snapshot outlives it
+	for _, name := range stateFiles {
+		if err := os.RemoveAll(filepath.Join(stateDir, name)); err != nil {
+			return nil, fmt.Errorf("failed to remove previous state: %w", err)
+		}
+	}
+
+	entries, err := os.ReadDir(stagingDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read staging directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() == store.StableStoreFile {
+			continue
+		}
+		if err := os.Rename(filepath.Join(stagingDir, entry.Name()), filepath.Join(stateDir, entry.Name())); err != nil {
+			return nil, fmt.Errorf("failed to move %s into place: %w", entry.Name(), err)
+		}
+	}
+	if err := syncDir(stateDir); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(filepath.Join(stagingDir, store.StableStoreFile), filepath.Join(stateDir, store.StableStoreFile)); err != nil {
+		return nil, fmt.Errorf("failed to move %s into place: %w", store.StableStoreFile, err)
+	}
+	if err := syncDir(stateDir); err != nil {
+		return nil, err
+	}
+
+	for _, file := range manifest.Files {
+		result.Installed = append(result.Installed, file.Path)
+	}
+	return result, nil
+}
+
+// openPayload returns the tar.gz payload of a bundle in either format
+func openPayload(bundlePath string) (io.ReadCloser, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+
+	magic, err := bufio.NewReader(f).Peek(2)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		return f, nil
+	}
+	f.Close()
+
+	layer, err := readOCILayer(bundlePath)
+	if err != nil {
+		if errors.Is(err, ErrChecksumMismatch) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	return io.NopCloser(bytes.NewReader(layer)), nil
+}
+
+// extractPayload unpacks the tar.gz payload into dir, verifying every file against the manifest
+func extractPayload(r io.Reader, dir string) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != ManifestFile {
+		return nil, fmt.Errorf("%w: %s must be the first entry", ErrInvalidBundle, ManifestFile)
+	}
+	manifest := &Manifest{}
+	if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode %s: %v", ErrInvalidBundle, ManifestFile, err)
+	}
+	if manifest.Version < 1 || manifest.Version > ManifestVersion {
+		return nil, fmt.Errorf("%w: unsupported manifest version %d", ErrInvalidBundle, manifest.Version)
+	}
+
+	pending := make(map[string]File, len(manifest.Files))
+	hasStableStore := false
+	for _, file := range manifest.Files {
+		hasStableStore = hasStableStore || file.Path == store.StableStoreFile
+		if !filepath.IsLocal(filepath.FromSlash(file.Path)) || path.Clean(file.Path) != file.Path {
+			return nil, fmt.Errorf("%w: invalid file path %q", ErrInvalidBundle, file.Path)
+		}
+		pending[file.Path] = file
+	}
+	if !hasStableStore {
+		return nil, fmt.Errorf("%w: %s is missing", ErrInvalidBundle, store.StableStoreFile)
+	}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+
+		file, ok := pending[header.Name]
+		if !ok || header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("%w: unexpected entry %q", ErrInvalidBundle, header.Name)
+		}
+		delete(pending, header.Name)
+
+		if err := extractFile(tr, filepath.Join(dir, filepath.FromSlash(file.Path)), file); err != nil {
+			return nil, err
+		}
+	}
+
+	for name := range pending {
+		return nil, fmt.Errorf("%w: %s is listed in the manifest but missing", ErrInvalidBundle, name)
+	}
+	return manifest, nil
+}
+
+func extractFile(r io.Reader, dst string, file File) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", file.Path, err)
+	}
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", file.Path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", file.Path, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); size != file.Size || sum != file.SHA256 {
+		return fmt.Errorf("%w: %s has %d bytes with sha256 %s, expected %d bytes with sha256 %s",
+			ErrChecksumMismatch, file.Path, size, sum, file.Size, file.SHA256)
+	}
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", file.Path, err)
+	}
+	return f.Close()
+}
+
+// stateFiles are the Raft state files and directories, the stable store first
+var stateFiles = []string{store.StableStoreFile, store.LogStoreFile, store.SnapshotsDir}
+
+// ExistingState lists the Raft state files and directories present in stateDir. Without a stable
+// store there is no complete state, only what an interrupted install left behind, and nil is returned.
+func ExistingState(stateDir string) []string {
+	if _, err := os.Stat(filepath.Join(stateDir, store.StableStoreFile)); err != nil {
+		return nil
+	}
+	var existing []string
+	for _, name := range stateFiles {
+		if _, err := os.Stat(filepath.Join(stateDir, name)); err == nil {
+			existing = append(existing, name)
+		}
+	}
+	return existing
+}
+
+// compareState reports whether stateDir already holds exactly the bundled files,
+// and otherwise which state files it holds
+func compareState(stateDir string, manifest *Manifest) (bool, []string, error) {
+	existing := ExistingState(stateDir)
+	if len(existing) == 0 {
+		return false, nil, nil
+	}
+
+	for _, file := range manifest.Files {
+		sum, size, err := checksumFile(filepath.Join(stateDir, filepath.FromSlash(file.Path)))
+		if os.IsNotExist(err) {
+			return false, existing, nil
+		}
+		if err != nil {
+			return false, nil, fmt.Errorf("failed to check existing %s: %w", file.Path, err)
+		}
+		if sum != file.SHA256 || size != file.Size {
+			return false, existing, nil
+		}
+	}
+	return true, existing, nil
+}
+
+// syncDir flushes a directory, persisting the renaming of its entries
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open directory %s for sync: %w", path, err)
+	}
+	defer dir.Close()
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// OCI image layout media types and annotations used by OCI bundles
+const (
+	ociLayoutFile   = "oci-layout"
+	ociIndexFile    = "index.json"
+	ociBlobsDir     = "blobs/sha256/"
+	ociManifestType = "application/vnd.oci.image.manifest.v1+json"
+	ociEmptyType    = "application/vnd.oci.empty.v1+json"
+	ociLayerType    = "application/vnd.oci.image.layer.v1.tar+gzip"
+	// ArtifactType identifies op-conductor-init bundles in OCI registries
+	ArtifactType = "application/vnd.golem-base.op-conductor-init.bundle.v1"
+
+	ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+	ociTitleAnnotation   = "org.opencontainers.image.title"
+	ociCreatedAnnotation = "org.opencontainers.image.created"
+)
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// writeOCI writes an OCI image layout tarball whose only layer is the tar.gz bundle
+func writeOCI(w io.Writer, nodeDir string, manifest *Manifest) error {
+	var layer bytes.Buffer
+	if err := writePayload(&layer, nodeDir, manifest); err != nil {
+		return err
+	}
+
+	empty := []byte("{}")
+	imageManifest, err := json.Marshal(&ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestType,
+		ArtifactType:  ArtifactType,
+		Config:        descriptor(ociEmptyType, empty, nil),
+		Layers: []ociDescriptor{descriptor(ociLayerType, layer.Bytes(), map[string]string{
+			ociTitleAnnotation: FileName(manifest.ServerID, FormatTarGz),
+		})},
+		Annotations: map[string]string{
+			ociCreatedAnnotation: manifest.GeneratedAt.UTC().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode OCI manifest: %w", err)
+	}
+	index, err := json.Marshal(&ociIndex{
+		SchemaVersion: 2,
+		MediaType:     "application/vnd.oci.image.index.v1+json",
+		Manifests: []ociDescriptor{descriptor(ociManifestType, imageManifest, map[string]string{
+			ociRefNameAnnotation: manifest.ServerID,
+		})},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode OCI index: %w", err)
+	}
+
+	tw := tar.NewWriter(w)
+	entries := []struct {
+		name string
+		data []byte
+	}{
+		{ociLayoutFile, []byte(`{"imageLayoutVersion":"1.0.0"}`)},
+		{ociIndexFile, index},
+		{blobPath(imageManifest), imageManifest},
+		{blobPath(empty), empty},
+		{blobPath(layer.Bytes()), layer.Bytes()},
+	}
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.data)), ModTime: manifest.GeneratedAt}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.name, err)
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish OCI bundle: %w", err)
+	}
+	return nil
+}
+
+func descriptor(mediaType string, data []byte, annotations map[string]string) ociDescriptor {
+	return ociDescriptor{MediaType: mediaType, Digest: digest(data), Size: int64(len(data)), Annotations: annotations}
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func blobPath(data []byte) string {
+	sum := sha256.Sum256(data)
+	return ociBlobsDir + hex.EncodeToString(sum[:])
+}
+
+// readOCILayer resolves the bundle layer of an OCI image layout tarball and returns its contents.
+// The digest of every blob on the way is verified.
+func readOCILayer(path string) ([]byte, error) {
+	index := &ociIndex{}
+	if err := readOCIJSON(path, ociIndexFile, "", index); err != nil {
+		return nil, err
+	}
+	if len(index.Manifests) != 1 {
+		return nil, fmt.Errorf("expected exactly one manifest in OCI index, got %d", len(index.Manifests))
+	}
+
+	imageManifest := &ociManifest{}
+	if err := readOCIJSON(path, blobName(index.Manifests[0].Digest), index.Manifests[0].Digest, imageManifest); err != nil {
+		return nil, err
+	}
+	if imageManifest.ArtifactType != ArtifactType || len(imageManifest.Layers) != 1 || imageManifest.Layers[0].MediaType != ociLayerType {
+		return nil, fmt.Errorf("OCI image is not an op-conductor-init bundle")
+	}
+
+	layer := imageManifest.Layers[0]
+	return readTarEntry(path, blobName(layer.Digest), layer.Digest)
+}
+
+func readOCIJSON(path, name, expectedDigest string, v interface{}) error {
+	data, err := readTarEntry(path, name, expectedDigest)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return nil
+}
+
+func blobName(digest string) string {
+	if len(digest) > len("sha256:") {
+		return ociBlobsDir + digest[len("sha256:"):]
+	}
+	return ociBlobsDir + digest
+}
+
+// readTarEntry reads a single entry of an uncompressed tarball and checks its digest, if given
+func readTarEntry(path, name, expectedDigest string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in OCI bundle", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OCI bundle: %w", err)
+		}
+		if header.Name != name && header.Name != "./"+name {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if expectedDigest != "" && digest(data) != expectedDigest {
+			return nil, fmt.Errorf("%w: %s has digest %s, expected %s", ErrChecksumMismatch, name, digest(data), expectedDigest)
+		}
+		return data, nil
+	}
+}
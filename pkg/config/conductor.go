@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+
+	opcflags "github.com/ethereum-optimism/optimism/op-conductor/flags"
+)
+
+// ConductorListenAddr is the consensus listen address recommended for generated nodes
+const ConductorListenAddr = "0.0.0.0"
+
+// ConductorFlag is an op-conductor flag recommended for running a node from generated state
+type ConductorFlag struct {
+	Name   string `json:"name" yaml:"name"`
+	EnvVar string `json:"envVar" yaml:"envVar"`
+	Value  string `json:"value" yaml:"value"`
+}
+
+// Arg returns the flag as a command line argument
+func (f ConductorFlag) Arg() string {
+	return fmt.Sprintf("--%s=%s", f.Name, f.Value)
+}
+
+// Env returns the flag as an environment variable assignment
+func (f ConductorFlag) Env() string {
+	return fmt.Sprintf("%s=%s", f.EnvVar, f.Value)
+}
+
+// ConductorFlags returns the op-conductor flags of a node, with the configured storage directory
+func (c *Config) ConductorFlags(node NodeConfig) ([]ConductorFlag, error) {
+	return ConductorFlags(node, c.ConductorStorageDir)
+}
+
+// ConductorFlags returns the op-conductor flags a node needs to start from generated state.
+// The names come from op-conductor's own flag definitions, so they cannot drift apart.
+// --raft.storage.dir is omitted when storageDir is empty.
+func ConductorFlags(node NodeConfig, storageDir string) ([]ConductorFlag, error) {
+	_, port, err := net.SplitHostPort(node.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse address of %s: %w", node.ServerID, err)
+	}
+
+	flags := []ConductorFlag{
+		conductorFlag(opcflags.RaftServerID, node.ServerID),
+		conductorFlag(opcflags.RaftBootstrap, strconv.FormatBool(false)),
+		conductorFlag(opcflags.ConsensusAddr, ConductorListenAddr),
+		conductorFlag(opcflags.ConsensusPort, port),
+		conductorFlag(opcflags.AdvertisedFullAddr, node.Address),
+	}
+	if storageDir != "" {
+		flags = append(flags, conductorFlag(opcflags.RaftStorageDir, storageDir))
+	}
+	return flags, nil
+}
+
+func conductorFlag(flag cli.DocGenerationFlag, value string) ConductorFlag {
+	return ConductorFlag{
+		Name:   flag.Names()[0],
+		EnvVar: flag.GetEnvVars()[0],
+		Value:  value,
+	}
+}
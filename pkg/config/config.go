@@ -2,28 +2,114 @@ package config
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/hashicorp/raft"
 	"github.com/urfave/cli/v2"
 
 	"github.com/golem-base/op-conductor-init/pkg/flags"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+// Generation modes
+const (
+	// ModeLog writes the membership configuration as the first log entry
+	ModeLog = "log"
+	// ModeSnapshot writes the membership configuration into a snapshot, as a compacted cluster would have it
+	ModeSnapshot = "snapshot"
+)
+
+// Bundle formats
+const (
+	BundleTarGz = "tar.gz"
+	BundleOCI   = "oci"
+)
+
+// Deployment manifest kinds written by --emit
+const (
+	EmitK8s = "k8s"
+)
+
+// Kubernetes resources holding node bundles
+const (
+	K8sConfigMap = "configmap"
+	K8sSecret    = "secret"
+)
+
+// Special --initial-leader values
+const (
+	// NoInitialLeader selects leaderless generation
+	NoInitialLeader = "none"
+	// AutoInitialLeader selects the voter whose op-node reports the highest unsafe head
+	AutoInitialLeader = "auto"
 )
 
 // NodeConfig represents a single node in the Raft cluster
 type NodeConfig struct {
 	ServerID string
 	Address  string
+	Suffrage raft.ServerSuffrage
+	Labels   map[string]string
+	// RPC is the node's op-node RPC endpoint, used by --initial-leader auto
+	RPC string
+}
+
+// K8sConfig holds the settings of the Kubernetes manifests written by --emit k8s
+type K8sConfig struct {
+	Namespace string
+	Image     string
+	// Resource is K8sConfigMap or K8sSecret
+	Resource string
+	// StatefulSet is the name of each node's StatefulSet, {id} is replaced with the server ID
+	StatefulSet string
+	Volume      string
 }
 
 // Config holds the configuration for the raft-preconfig tool
 type Config struct {
-	Nodes         []NodeConfig
-	OutputDir     string
+	Nodes     []NodeConfig
+	OutputDir string
+	// InitialLeader is empty for leaderless generation, where no node records a vote
 	InitialLeader string
 	InitialTerm   uint64
-	Network       string
-	Force         bool
+	// InitialIndex is the log index of the configuration entry in log mode
+	InitialIndex uint64
+	Network      string
+	Force        bool
+	// Self is the server ID of the calling node when only its own state is generated, into StateDir
+	Self     string
+	StateDir string
+	// Ensure only generates when no state exists and accepts existing state matching the configuration
+	Ensure bool
+
+	// UnsafePayloadFile and UnsafePayloadRPC select where the initial unsafe head payload is loaded from.
+	// At most one of them is set; when both are empty no payload entry is generated.
+	UnsafePayloadFile  string
+	UnsafePayloadRPC   string
+	UnsafePayloadBlock string
+
+	// Mode selects how the initial state is written, see ModeLog and ModeSnapshot
+	Mode          string
+	SnapshotIndex uint64
+
+	// ConductorStorageDir is op-conductor's --raft.storage.dir on the nodes, empty when unknown
+	ConductorStorageDir string
+	// ConductorFlagsJSON writes conductor-flags.json next to conductor.env
+	ConductorFlagsJSON bool
+
+	// Bundle is the format of the per-node deployment bundles, empty when none are written
+	Bundle string
+
+	// Emit selects additional deployment manifests, see EmitK8s
+	Emit string
+	K8s  K8sConfig
+
+	// Preflight resolves and dials every node before any state is written
+	Preflight        bool
+	PreflightTimeout time.Duration
 }
 
 // NewConfig creates a new Config from CLI context
@@ -32,40 +118,444 @@ func NewConfig(ctx *cli.Context, log log.Logger) (*Config, error) {
 		OutputDir:     ctx.String(flags.OutputDirFlag.Name),
 		InitialLeader: ctx.String(flags.InitialLeaderFlag.Name),
 		InitialTerm:   ctx.Uint64(flags.InitialTermFlag.Name),
+		InitialIndex:  ctx.Uint64(flags.InitialIndexFlag.Name),
 		Network:       ctx.String(flags.NetworkFlag.Name),
 		Force:         ctx.Bool(flags.ForceFlag.Name),
+		Ensure:        ctx.Bool(flags.EnsureFlag.Name),
+		Self:          ctx.String(flags.SelfFlag.Name),
+		StateDir:      ctx.String(flags.SelfStateDirFlag.Name),
+
+		UnsafePayloadFile:  ctx.String(flags.UnsafePayloadFlag.Name),
+		UnsafePayloadRPC:   ctx.String(flags.UnsafePayloadRPCFlag.Name),
+		UnsafePayloadBlock: ctx.String(flags.UnsafePayloadBlockFlag.Name),
+
+		Mode:          ctx.String(flags.ModeFlag.Name),
+		SnapshotIndex: ctx.Uint64(flags.SnapshotIndexFlag.Name),
+
+		ConductorStorageDir: ctx.String(flags.ConductorStorageDirFlag.Name),
+		ConductorFlagsJSON:  ctx.Bool(flags.ConductorFlagsJSONFlag.Name),
+
+		Bundle: ctx.String(flags.BundleFlag.Name),
+		Emit:   ctx.String(flags.EmitFlag.Name),
+		K8s: K8sConfig{
+			Namespace:   ctx.String(flags.NamespaceFlag.Name),
+			Image:       ctx.String(flags.K8sImageFlag.Name),
+			Resource:    ctx.String(flags.K8sResourceFlag.Name),
+			StatefulSet: ctx.String(flags.K8sStatefulSetFlag.Name),
+			Volume:      ctx.String(flags.K8sVolumeFlag.Name),
+		},
+
+		Preflight:        ctx.Bool(flags.PreflightFlag.Name),
+		PreflightTimeout: ctx.Duration(flags.PreflightTimeoutFlag.Name),
 	}
 
-	// Parse nodes and server IDs
-	nodes, err := parseNodes(ctx.String(flags.NodesFlag.Name), ctx.String(flags.ServerIDsFlag.Name))
-	if err != nil {
+	switch cfg.Mode {
+	case ModeLog:
+		if ctx.IsSet(flags.SnapshotIndexFlag.Name) {
+			return nil, fmt.Errorf("--%s requires --%s %s", flags.SnapshotIndexFlag.Name, flags.ModeFlag.Name, ModeSnapshot)
+		}
+	case ModeSnapshot:
+		if cfg.SnapshotIndex == 0 {
+			return nil, fmt.Errorf("--%s must be at least 1", flags.SnapshotIndexFlag.Name)
+		}
+	default:
+		return nil, fmt.Errorf("unknown generation mode %q (expected %s or %s)", cfg.Mode, ModeLog, ModeSnapshot)
+	}
+
+	if cfg.Ensure && cfg.Force {
+		return nil, fmt.Errorf("--%s and --%s are mutually exclusive", flags.EnsureFlag.Name, flags.ForceFlag.Name)
+	}
+
+	switch cfg.Bundle {
+	case "", BundleTarGz, BundleOCI:
+	default:
+		return nil, fmt.Errorf("unknown bundle format %q (expected %s or %s)", cfg.Bundle, BundleTarGz, BundleOCI)
+	}
+
+	if err := cfg.validateEmit(); err != nil {
+		return nil, err
+	}
+
+	if cfg.UnsafePayloadFile != "" && cfg.UnsafePayloadRPC != "" {
+		return nil, fmt.Errorf("--%s and --%s are mutually exclusive",
+			flags.UnsafePayloadFlag.Name, flags.UnsafePayloadRPCFlag.Name)
+	}
+
+	// The nodes come from exactly one source
+	var sources []string
+	for _, flag := range []cli.Flag{flags.SpecFlag, flags.ReplicasFlag, flags.FromConductorEnvFlag, flags.FromLiveFlag} {
+		if ctx.IsSet(flag.Names()[0]) {
+			sources = append(sources, "--"+flag.Names()[0])
+		}
+	}
+	if ctx.IsSet(flags.NodesFlag.Name) || ctx.IsSet(flags.ServerIDsFlag.Name) {
+		sources = append(sources, fmt.Sprintf("--%s/--%s", flags.NodesFlag.Name, flags.ServerIDsFlag.Name))
+	}
+	if len(sources) > 1 {
+		return nil, fmt.Errorf("%s cannot be combined, the nodes come from exactly one of them", strings.Join(sources, " and "))
+	}
+
+	switch {
+	case ctx.IsSet(flags.SpecFlag.Name):
+		if err := cfg.applySpec(ctx, ctx.String(flags.SpecFlag.Name)); err != nil {
+			return nil, err
+		}
+	case ctx.IsSet(flags.ReplicasFlag.Name):
+		nodes, err := templatedNodes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Nodes = nodes
+	case ctx.IsSet(flags.FromConductorEnvFlag.Name):
+		var paths []string
+		for _, path := range strings.Split(ctx.String(flags.FromConductorEnvFlag.Name), ",") {
+			paths = append(paths, strings.TrimSpace(path))
+		}
+		nodes, err := ImportConductorFiles(paths)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import op-conductor configuration: %w", err)
+		}
+		cfg.Nodes = nodes
+	case ctx.IsSet(flags.FromLiveFlag.Name):
+		if err := cfg.applyLive(ctx, log); err != nil {
+			return nil, err
+		}
+	default:
+		if !ctx.IsSet(flags.NodesFlag.Name) || !ctx.IsSet(flags.ServerIDsFlag.Name) {
+			return nil, fmt.Errorf("either --%s, --%s, --%s, --%s or both --%s and --%s are required", flags.SpecFlag.Name,
+				flags.ReplicasFlag.Name, flags.FromConductorEnvFlag.Name, flags.FromLiveFlag.Name, flags.NodesFlag.Name, flags.ServerIDsFlag.Name)
+		}
+
+		// Parse nodes and server IDs
+		nodes, err := parseNodes(ctx.String(flags.NodesFlag.Name), ctx.String(flags.ServerIDsFlag.Name))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Nodes = nodes
+	}
+
+	if err := cfg.applyPosition(ctx, log); err != nil {
+		return nil, err
+	}
+
+	if dir := ctx.String(flags.BumpTermFromFlag.Name); dir != "" {
+		if ctx.IsSet(flags.InitialTermFlag.Name) {
+			return nil, fmt.Errorf("--%s and --%s are mutually exclusive", flags.BumpTermFromFlag.Name, flags.InitialTermFlag.Name)
+		}
+		term, err := bumpedTerm(dir)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("Bumped initial term above the cluster's current term", "state_dir", dir, "initial_term", term)
+		cfg.InitialTerm = term
+	}
+
+	if err := applyRPCs(cfg.Nodes, ctx.String(flags.NodeRPCsFlag.Name)); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateNodes(cfg.Nodes); err != nil {
+		return nil, fmt.Errorf("invalid cluster nodes: %w", err)
+	}
+	// Every node's init patch targets the StatefulSet named here, they must not all patch the same one
+	if cfg.Emit == EmitK8s && len(cfg.Nodes) > 1 && !strings.Contains(cfg.K8s.StatefulSet, "{id}") {
+		return nil, fmt.Errorf("--%s %q must contain {id} with %d nodes, otherwise every pod of the StatefulSet installs the same node's bundle",
+			flags.K8sStatefulSetFlag.Name, cfg.K8s.StatefulSet, len(cfg.Nodes))
+	}
+
+	if err := applySuffrage(cfg.Nodes, ctx.String(flags.NonVotersFlag.Name), raft.Nonvoter); err != nil {
+		return nil, err
+	}
+	if err := applySuffrage(cfg.Nodes, ctx.String(flags.StagingFlag.Name), raft.Staging); err != nil {
 		return nil, err
 	}
-	cfg.Nodes = nodes
+
+	if ctx.Bool(flags.NoInitialLeaderFlag.Name) {
+		if ctx.IsSet(flags.InitialLeaderFlag.Name) && cfg.InitialLeader != NoInitialLeader {
+			return nil, fmt.Errorf("--%s and --%s are mutually exclusive",
+				flags.InitialLeaderFlag.Name, flags.NoInitialLeaderFlag.Name)
+		}
+		cfg.InitialLeader = NoInitialLeader
+	}
 
 	// Validate initial leader exists
-	leaderFound := false
-	for _, node := range cfg.Nodes {
+	switch cfg.InitialLeader {
+	case "":
+		return nil, fmt.Errorf("--%s is required (use --%s to leave the leader to the first election)",
+			flags.InitialLeaderFlag.Name, flags.NoInitialLeaderFlag.Name)
+	case NoInitialLeader:
+		cfg.InitialLeader = ""
+	}
+	var leader *NodeConfig
+	voters := 0
+	var missingRPCs []string
+	for i, node := range cfg.Nodes {
 		if node.ServerID == cfg.InitialLeader {
-			leaderFound = true
-			break
+			leader = &cfg.Nodes[i]
+		}
+		if node.Suffrage == raft.Voter {
+			voters++
+			if node.RPC == "" {
+				missingRPCs = append(missingRPCs, node.ServerID)
+			}
 		}
 	}
-	if !leaderFound {
+	if cfg.InitialLeader == AutoInitialLeader {
+		// The leader is selected at generation time, every voter must be queryable
+		if len(missingRPCs) > 0 {
+			return nil, fmt.Errorf("--%s %s requires an op-node RPC for every voter, missing for %s",
+				flags.InitialLeaderFlag.Name, AutoInitialLeader, strings.Join(missingRPCs, ", "))
+		}
+	} else if leader == nil && cfg.InitialLeader != "" {
 		return nil, fmt.Errorf("initial leader %s not found in server IDs", cfg.InitialLeader)
 	}
+	if leader != nil && leader.Suffrage != raft.Voter {
+		return nil, fmt.Errorf("initial leader %s must be a voter, got %s", cfg.InitialLeader, leader.Suffrage)
+	}
+	if voters == 0 {
+		return nil, fmt.Errorf("cluster must contain at least one voter")
+	}
+
+	if err := cfg.validateSelf(); err != nil {
+		return nil, err
+	}
 
 	// Log configuration
 	log.Info("Loaded configuration",
 		"nodes", len(cfg.Nodes),
-		"initial_leader", cfg.InitialLeader,
+		"voters", voters,
+		"initial_leader", cfg.LeaderName(),
 		"initial_term", cfg.InitialTerm,
+		"mode", cfg.Mode,
 		"output_dir", cfg.OutputDir,
 	)
 
 	return cfg, nil
 }
 
+// validateSelf checks that --self generation is deterministic, so every node computes identical
+// configuration entries from the same spec without coordinating with the others
+func (c *Config) validateSelf() error {
+	if c.Self == "" {
+		if c.StateDir != "" {
+			return fmt.Errorf("--%s requires --%s", flags.SelfStateDirFlag.Name, flags.SelfFlag.Name)
+		}
+		return nil
+	}
+
+	found := false
+	for _, node := range c.Nodes {
+		found = found || node.ServerID == c.Self
+	}
+	switch {
+	case !found:
+		return fmt.Errorf("--%s %s not found in server IDs", flags.SelfFlag.Name, c.Self)
+	case c.StateDir == "":
+		return fmt.Errorf("--%s requires --%s", flags.SelfFlag.Name, flags.SelfStateDirFlag.Name)
+	case c.Force || c.Ensure:
+		return fmt.Errorf("--%s never replaces existing state and cannot be combined with --%s or --%s",
+			flags.SelfFlag.Name, flags.ForceFlag.Name, flags.EnsureFlag.Name)
+	case c.Bundle != "" || c.Emit != "":
+		return fmt.Errorf("--%s cannot be combined with --%s or --%s", flags.SelfFlag.Name, flags.BundleFlag.Name, flags.EmitFlag.Name)
+	case c.InitialLeader == AutoInitialLeader:
+		// Nodes starting at different times could observe different heads and pick different leaders
+		return fmt.Errorf("--%s cannot be combined with --%s %s", flags.SelfFlag.Name, flags.InitialLeaderFlag.Name, AutoInitialLeader)
+	}
+
+	if c.UnsafePayloadRPC != "" {
+		if _, err := strconv.ParseUint(strings.TrimSpace(c.UnsafePayloadBlock), 0, 64); err != nil {
+			return fmt.Errorf("--%s with --%s requires a block number in --%s, so every node records the same payload",
+				flags.SelfFlag.Name, flags.UnsafePayloadRPCFlag.Name, flags.UnsafePayloadBlockFlag.Name)
+		}
+	}
+	if c.ConductorStorageDir == "" {
+		c.ConductorStorageDir = c.StateDir
+	}
+	return nil
+}
+
+// applyPosition sets where the generated log starts, either explicitly or strictly above an old node's state.
+// A log starting above index 1 is anchored by a snapshot carrying the unsafe payload as FSM state, since
+// raft applies every entry from index 1 on otherwise and op-conductor cannot restore an empty snapshot.
+func (c *Config) applyPosition(ctx *cli.Context, log log.Logger) error {
+	if dir := ctx.String(flags.AboveStateFlag.Name); dir != "" {
+		for _, flag := range []cli.Flag{flags.InitialIndexFlag, flags.InitialTermFlag, flags.SnapshotIndexFlag, flags.BumpTermFromFlag} {
+			if ctx.IsSet(flag.Names()[0]) {
+				return fmt.Errorf("--%s and --%s are mutually exclusive", flags.AboveStateFlag.Name, flag.Names()[0])
+			}
+		}
+		position, err := store.LastPosition(dir)
+		if err != nil {
+			return fmt.Errorf("failed to read last position from %s: %w", dir, err)
+		}
+		c.InitialTerm = position.Term + 1
+		if c.Mode == ModeSnapshot {
+			c.SnapshotIndex = position.Index + 1
+		} else {
+			c.InitialIndex = position.Index + 1
+		}
+		log.Info("Continuing above existing state", "state_dir", dir, "last_index", position.Index, "last_term", position.Term)
+	}
+
+	hasPayload := c.UnsafePayloadFile != "" || c.UnsafePayloadRPC != ""
+	if c.Mode == ModeSnapshot && !hasPayload {
+		return fmt.Errorf("--%s %s requires an unsafe payload (--%s or --%s), op-conductor's FSM cannot restore an empty snapshot",
+			flags.ModeFlag.Name, ModeSnapshot, flags.UnsafePayloadFlag.Name, flags.UnsafePayloadRPCFlag.Name)
+	}
+
+	switch {
+	case c.InitialIndex == 0:
+		return fmt.Errorf("--%s must be at least 1", flags.InitialIndexFlag.Name)
+	case c.InitialIndex == 1:
+	case c.Mode == ModeSnapshot:
+		return fmt.Errorf("--%s requires --%s %s, use --%s in snapshot mode",
+			flags.InitialIndexFlag.Name, flags.ModeFlag.Name, ModeLog, flags.SnapshotIndexFlag.Name)
+	case !hasPayload:
+		return fmt.Errorf("a log starting at index %d requires an unsafe payload (--%s or --%s) for its anchoring snapshot",
+			c.InitialIndex, flags.UnsafePayloadFlag.Name, flags.UnsafePayloadRPCFlag.Name)
+	}
+	return nil
+}
+
+// validateEmit checks the deployment manifest settings.
+// Kubernetes manifests carry the tar.gz bundle of every node, so --emit k8s implies --bundle tar.gz.
+func (c *Config) validateEmit() error {
+	switch c.Emit {
+	case "":
+		return nil
+	case EmitK8s:
+	default:
+		return fmt.Errorf("unknown --%s value %q (expected %s)", flags.EmitFlag.Name, c.Emit, EmitK8s)
+	}
+
+	switch c.Bundle {
+	case "":
+		c.Bundle = BundleTarGz
+	case BundleTarGz:
+	default:
+		return fmt.Errorf("--%s %s requires --%s %s", flags.EmitFlag.Name, EmitK8s, flags.BundleFlag.Name, BundleTarGz)
+	}
+	if c.ConductorStorageDir == "" {
+		return fmt.Errorf("--%s %s requires --%s, the mount path of the state volume", flags.EmitFlag.Name, EmitK8s, flags.ConductorStorageDirFlag.Name)
+	}
+	if c.K8s.Resource != K8sConfigMap && c.K8s.Resource != K8sSecret {
+		return fmt.Errorf("unknown --%s %q (expected %s or %s)", flags.K8sResourceFlag.Name, c.K8s.Resource, K8sConfigMap, K8sSecret)
+	}
+	if c.K8s.Image == "" || c.K8s.StatefulSet == "" || c.K8s.Volume == "" {
+		return fmt.Errorf("--%s, --%s and --%s must not be empty with --%s %s",
+			flags.K8sImageFlag.Name, flags.K8sStatefulSetFlag.Name, flags.K8sVolumeFlag.Name, flags.EmitFlag.Name, EmitK8s)
+	}
+	return nil
+}
+
+// LeaderName returns the initial leader for display, or "none" for leaderless generation
+func (c *Config) LeaderName() string {
+	if c.InitialLeader == "" {
+		return NoInitialLeader
+	}
+	return c.InitialLeader
+}
+
+// applySpec loads the cluster spec file into the config.
+// Explicitly set CLI flags take precedence over the values in the spec.
+func (c *Config) applySpec(ctx *cli.Context, path string) error {
+	spec, err := LoadSpec(path)
+	if err != nil {
+		return err
+	}
+	if err := spec.Validate(); err != nil {
+		return fmt.Errorf("invalid cluster spec %s: %w", path, err)
+	}
+
+	nodes, err := spec.NodeConfigs()
+	if err != nil {
+		return err
+	}
+	c.Nodes = nodes
+
+	if !ctx.IsSet(flags.InitialLeaderFlag.Name) {
+		c.InitialLeader = strings.TrimSpace(spec.InitialLeader)
+	}
+	if !ctx.IsSet(flags.InitialTermFlag.Name) && spec.InitialTerm != 0 {
+		c.InitialTerm = spec.InitialTerm
+	}
+	if !ctx.IsSet(flags.NetworkFlag.Name) {
+		c.Network = spec.Network
+	}
+
+	return nil
+}
+
+// templatedNodes expands the --replicas node templates
+func templatedNodes(ctx *cli.Context) ([]NodeConfig, error) {
+	if !ctx.IsSet(flags.IDTemplateFlag.Name) || !ctx.IsSet(flags.AddressTemplateFlag.Name) {
+		return nil, fmt.Errorf("--%s requires --%s and --%s",
+			flags.ReplicasFlag.Name, flags.IDTemplateFlag.Name, flags.AddressTemplateFlag.Name)
+	}
+
+	overrides, err := parseOverrides(ctx.String(flags.AddressOverridesFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	template := &NodeTemplate{
+		Replicas:         ctx.Int(flags.ReplicasFlag.Name),
+		Offset:           ctx.Int(flags.OrdinalOffsetFlag.Name),
+		Namespace:        ctx.String(flags.NamespaceFlag.Name),
+		IDTemplate:       ctx.String(flags.IDTemplateFlag.Name),
+		AddressTemplate:  ctx.String(flags.AddressTemplateFlag.Name),
+		RPCTemplate:      ctx.String(flags.RPCTemplateFlag.Name),
+		AddressOverrides: overrides,
+	}
+	nodes, err := template.Expand()
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand node templates: %w", err)
+	}
+
+	return nodes, nil
+}
+
+// applySuffrage sets the given suffrage on every node listed in the comma-separated server IDs
+func applySuffrage(nodes []NodeConfig, serverIDsFlag string, suffrage raft.ServerSuffrage) error {
+	if strings.TrimSpace(serverIDsFlag) == "" {
+		return nil
+	}
+
+	for _, id := range strings.Split(serverIDsFlag, ",") {
+		id = strings.TrimSpace(id)
+		found := false
+		for i := range nodes {
+			if nodes[i].ServerID == id {
+				nodes[i].Suffrage = suffrage
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s server %s not found in server IDs", strings.ToLower(suffrage.String()), id)
+		}
+	}
+
+	return nil
+}
+
+// applyRPCs sets the op-node RPC endpoints from the comma-separated flag, in node order
+func applyRPCs(nodes []NodeConfig, rpcsFlag string) error {
+	if strings.TrimSpace(rpcsFlag) == "" {
+		return nil
+	}
+
+	rpcs := strings.Split(rpcsFlag, ",")
+	if len(rpcs) != len(nodes) {
+		return fmt.Errorf("number of node RPCs (%d) must match number of nodes (%d)", len(rpcs), len(nodes))
+	}
+	for i := range nodes {
+		nodes[i].RPC = strings.TrimSpace(rpcs[i])
+	}
+
+	return nil
+}
+
 // parseNodes parses the nodes and server IDs flags into NodeConfig slice
 func parseNodes(nodesFlag, serverIDsFlag string) ([]NodeConfig, error) {
 	nodeAddrs := strings.Split(nodesFlag, ",")
@@ -81,6 +571,7 @@ func parseNodes(nodesFlag, serverIDsFlag string) ([]NodeConfig, error) {
 		nodes[i] = NodeConfig{
 			ServerID: strings.TrimSpace(serverIDs[i]),
 			Address:  strings.TrimSpace(nodeAddrs[i]),
+			Suffrage: raft.Voter,
 		}
 	}
 
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/hashicorp/raft"
+	"github.com/urfave/cli/v2"
+
+	"github.com/golem-base/op-conductor-init/pkg/flags"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+func newTestContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+	set := flag.NewFlagSet("generate", flag.ContinueOnError)
+	for _, f := range flags.Flags {
+		if err := f.Apply(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+func TestNewConfigInitialLeader(t *testing.T) {
+	nodes := []string{
+		"--nodes", "sequencer-1:50050,sequencer-2:50050,sequencer-3:50050",
+		"--server-ids", "sequencer-1,sequencer-2,sequencer-3",
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		leader string
+		err    string
+	}{
+		{name: "leader", args: []string{"--initial-leader", "sequencer-2"}, leader: "sequencer-2"},
+		{name: "no initial leader flag", args: []string{"--no-initial-leader"}, leader: ""},
+		{name: "initial leader none", args: []string{"--initial-leader", "none"}, leader: ""},
+		{name: "both", args: []string{"--no-initial-leader", "--initial-leader", "sequencer-1"}, err: "mutually exclusive"},
+		{name: "missing", args: nil, err: "--initial-leader is required"},
+		{name: "auto", args: []string{"--initial-leader", "auto", "--node-rpcs", "http://op-node-1:9545,http://op-node-2:9545,http://op-node-3:9545"}, leader: "auto"},
+		{name: "auto without rpcs", args: []string{"--initial-leader", "auto"}, err: "requires an op-node RPC for every voter"},
+		{name: "auto and no initial leader", args: []string{"--initial-leader", "auto", "--no-initial-leader"}, err: "mutually exclusive"},
+		{name: "unknown leader", args: []string{"--initial-leader", "sequencer-9"}, err: "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewConfig(newTestContext(t, append(nodes, tt.args...)...), log.New())
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to create config: %v", err)
+			}
+			if cfg.InitialLeader != tt.leader {
+				t.Fatalf("Expected initial leader %q, got %q", tt.leader, cfg.InitialLeader)
+			}
+		})
+	}
+}
+
+func TestNewConfigReplicas(t *testing.T) {
+	cfg, err := NewConfig(newTestContext(t,
+		"--replicas", "3",
+		"--id-template", "sequencer-{i}",
+		"--address-template", "sequencer-{i}.seq.{ns}.svc.cluster.local:50050",
+		"--namespace", "mainnet",
+		"--address-overrides", "sequencer-0=10.0.0.1:50050",
+		"--non-voters", "sequencer-2",
+		"--initial-leader", "sequencer-1",
+	), log.New())
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+
+	if len(cfg.Nodes) != 3 {
+		t.Fatalf("Expected 3 nodes, got %d", len(cfg.Nodes))
+	}
+	if node := cfg.Nodes[0]; node.ServerID != "sequencer-0" || node.Address != "10.0.0.1:50050" {
+		t.Fatalf("Unexpected first node: %+v", node)
+	}
+	if node := cfg.Nodes[2]; node.Address != "sequencer-2.seq.mainnet.svc.cluster.local:50050" || node.Suffrage != raft.Nonvoter {
+		t.Fatalf("Unexpected last node: %+v", node)
+	}
+
+	_, err = NewConfig(newTestContext(t,
+		"--replicas", "3",
+		"--id-template", "sequencer-{i}",
+		"--address-template", "sequencer-{i}:50050",
+		"--nodes", "sequencer-0:50050",
+		"--initial-leader", "sequencer-0",
+	), log.New())
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Fatalf("Expected --replicas and --nodes to conflict, got: %v", err)
+	}
+}
+
+func TestNewConfigAboveState(t *testing.T) {
+	oldDir := t.TempDir()
+	configuration := raft.Configuration{Servers: []raft.Server{{Suffrage: raft.Voter, ID: "sequencer-1", Address: "sequencer-1:50050"}}}
+	if err := store.CreateStableStore(oldDir, "sequencer-1", 6, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateLogStore(oldDir,
+		&raft.Log{Index: 40, Term: 4, Type: raft.LogConfiguration, Data: raft.EncodeConfiguration(configuration)},
+		&raft.Log{Index: 41, Term: 7, Type: raft.LogNoop},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := []string{"--nodes", "sequencer-1:50050", "--server-ids", "sequencer-1", "--initial-leader", "sequencer-1"}
+	cfg, err := NewConfig(newTestContext(t, append(nodes, "--above-state", oldDir, "--unsafe-payload", "payload.json")...), log.New())
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	if cfg.InitialIndex != 42 || cfg.InitialTerm != 8 {
+		t.Fatalf("Expected state to continue at index 42 in term 8, got index %d in term %d", cfg.InitialIndex, cfg.InitialTerm)
+	}
+
+	cfg, err = NewConfig(newTestContext(t, append(nodes, "--above-state", oldDir, "--mode", "snapshot", "--unsafe-payload", "payload.json")...), log.New())
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	if cfg.SnapshotIndex != 42 || cfg.InitialIndex != 1 {
+		t.Fatalf("Expected snapshot at index 42, got %d (initial index %d)", cfg.SnapshotIndex, cfg.InitialIndex)
+	}
+
+	for _, args := range [][]string{
+		{"--above-state", oldDir},
+		{"--initial-index", "10"},
+		{"--above-state", oldDir, "--initial-term", "3"},
+		{"--initial-index", "10", "--mode", "snapshot", "--unsafe-payload", "payload.json"},
+		{"--above-state", oldDir, "--mode", "snapshot"},
+		{"--mode", "snapshot"},
+	} {
+		if _, err := NewConfig(newTestContext(t, append(nodes, args...)...), log.New()); err == nil {
+			t.Fatalf("Expected %v to be rejected", args)
+		}
+	}
+}
+
+func TestNewConfigK8sStatefulSet(t *testing.T) {
+	emit := []string{"--initial-leader", "sequencer-1", "--emit", "k8s", "--conductor-storage-dir", "/data/raft"}
+
+	tests := []struct {
+		name        string
+		nodes       []string
+		statefulSet string
+		err         string
+	}{
+		{name: "per node", nodes: []string{"sequencer-1", "sequencer-2", "sequencer-3"}, statefulSet: "{id}"},
+		{name: "shared", nodes: []string{"sequencer-1", "sequencer-2", "sequencer-3"}, statefulSet: "sequencer", err: "must contain {id} with 3 nodes"},
+		{name: "single node", nodes: []string{"sequencer-1"}, statefulSet: "sequencer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addresses := make([]string, len(tt.nodes))
+			for i, id := range tt.nodes {
+				addresses[i] = id + ":50050"
+			}
+			args := append([]string{
+				"--nodes", strings.Join(addresses, ","),
+				"--server-ids", strings.Join(tt.nodes, ","),
+				"--k8s-statefulset", tt.statefulSet,
+			}, emit...)
+
+			_, err := NewConfig(newTestContext(t, args...), log.New())
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewConfigIgnoresInstallStateDir(t *testing.T) {
+	// Set for raft install or info in the same init container
+	t.Setenv("OP_CONDUCTOR_INIT_STATE_DIR", "/data/raft")
+
+	cfg, err := NewConfig(newTestContext(t,
+		"--nodes", "sequencer-1:50050,sequencer-2:50050",
+		"--server-ids", "sequencer-1,sequencer-2",
+		"--initial-leader", "sequencer-1",
+	), log.New())
+	if err != nil {
+		t.Fatalf("Expected generate to ignore OP_CONDUCTOR_INIT_STATE_DIR, got: %v", err)
+	}
+	if cfg.StateDir != "" {
+		t.Fatalf("Expected no state directory without --self, got %s", cfg.StateDir)
+	}
+}
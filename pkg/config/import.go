@@ -0,0 +1,171 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/raft"
+	"github.com/urfave/cli/v2"
+
+	opcflags "github.com/ethereum-optimism/optimism/op-conductor/flags"
+)
+
+// importedFlags are the op-conductor flags a node is derived from
+var importedFlags = []cli.DocGenerationFlag{
+	opcflags.RaftServerID,
+	opcflags.ConsensusAddr,
+	opcflags.ConsensusPort,
+	opcflags.AdvertisedFullAddr,
+	opcflags.NodeRPC,
+}
+
+// ImportConductorFiles derives the cluster nodes from the configuration files of running op-conductors,
+// one file per node, in the given order. Every problem in every file is reported.
+func ImportConductorFiles(paths []string) ([]NodeConfig, error) {
+	var result *multierror.Error
+	nodes := make([]NodeConfig, 0, len(paths))
+	for _, path := range paths {
+		node, err := ImportConductorFile(path)
+		if err != nil {
+			result = multierror.Append(result, err)
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, result.ErrorOrNil()
+}
+
+// ImportConductorFile derives a node from an op-conductor env file, flag file or conductor-flags.json.
+// Env files hold OP_CONDUCTOR_* assignments, flag files hold --name=value arguments; both may be mixed,
+// in which case arguments take precedence like they do in op-conductor.
+func ImportConductorFile(path string) (NodeConfig, error) {
+	var args, env map[string]string
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		args, err = readConductorJSON(path)
+	} else {
+		args, env, err = readConductorFile(path)
+	}
+	if err != nil {
+		return NodeConfig{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	value := func(flag cli.DocGenerationFlag) string {
+		if v, ok := args[flag.Names()[0]]; ok {
+			return v
+		}
+		return env[flag.GetEnvVars()[0]]
+	}
+
+	node := NodeConfig{
+		ServerID: value(opcflags.RaftServerID),
+		Suffrage: raft.Voter,
+		RPC:      value(opcflags.NodeRPC),
+	}
+	if node.ServerID == "" {
+		return NodeConfig{}, fmt.Errorf("%s: %s is not set", path, describeFlag(opcflags.RaftServerID))
+	}
+
+	// Peers reach a node at its advertised address, which defaults to the listen address
+	if advertised := value(opcflags.AdvertisedFullAddr); advertised != "" {
+		node.Address = advertised
+		return node, nil
+	}
+	host := value(opcflags.ConsensusAddr)
+	if host == "" {
+		host = opcflags.ConsensusAddr.Value
+	}
+	port := value(opcflags.ConsensusPort)
+	if port == "" {
+		port = strconv.Itoa(opcflags.ConsensusPort.Value)
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsUnspecified() || ip.IsLoopback()) {
+		return NodeConfig{}, fmt.Errorf("%s: %s listens on %s, which other nodes cannot reach, set %s",
+			path, node.ServerID, host, describeFlag(opcflags.AdvertisedFullAddr))
+	}
+	node.Address = net.JoinHostPort(host, port)
+	return node, nil
+}
+
+// readConductorFile parses a file of env assignments and command line arguments.
+// Blank lines, comments and other variables are skipped; an export prefix and quotes are accepted.
+func readConductorFile(path string) (map[string]string, map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	names := make(map[string]bool)
+	envVars := make(map[string]bool)
+	for _, flag := range importedFlags {
+		names[flag.Names()[0]] = true
+		envVars[flag.GetEnvVars()[0]] = true
+	}
+
+	args := make(map[string]string)
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Arguments may be continued shell lines, e.g. from a docker run command
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\\"))
+		if strings.HasPrefix(line, "-") {
+			name, value, ok := strings.Cut(strings.TrimLeft(line, "-"), "=")
+			if !ok {
+				name, value, _ = strings.Cut(name, " ")
+			}
+			if names[name] {
+				args[name] = unquote(strings.TrimSpace(value))
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("line %d: expected KEY=VALUE or --flag=value", lineNo)
+		}
+		if key = strings.TrimSpace(key); envVars[key] {
+			env[key] = unquote(strings.TrimSpace(value))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return args, env, nil
+}
+
+// readConductorJSON parses a JSON object of flag names and values, like the conductor-flags.json written by generate
+func readConductorJSON(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var args map[string]string
+	if err := json.Unmarshal(data, &args); err != nil {
+		return nil, fmt.Errorf("failed to parse op-conductor flags: %w", err)
+	}
+	return args, nil
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func describeFlag(flag cli.DocGenerationFlag) string {
+	return fmt.Sprintf("--%s (%s)", flag.Names()[0], flag.GetEnvVars()[0])
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func writeConductorFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportConductorFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		address string
+		rpc     string
+		err     string
+	}{
+		{
+			name: "env file",
+			file: "sequencer-1.env",
+			content: `# op-conductor
+export OP_CONDUCTOR_RAFT_SERVER_ID="sequencer-1"
+OP_CONDUCTOR_CONSENSUS_ADDR=0.0.0.0
+OP_CONDUCTOR_CONSENSUS_ADVERTISED=sequencer-1.seq.svc:50050
+OP_CONDUCTOR_NODE_RPC='http://op-node-1:9545'
+OP_CONDUCTOR_EXECUTION_RPC=http://op-geth-1:8545
+`,
+			address: "sequencer-1.seq.svc:50050",
+			rpc:     "http://op-node-1:9545",
+		},
+		{
+			name:    "listen address",
+			file:    "sequencer-1.env",
+			content: "OP_CONDUCTOR_RAFT_SERVER_ID=sequencer-1\nOP_CONDUCTOR_CONSENSUS_ADDR=10.0.0.1\nOP_CONDUCTOR_CONSENSUS_PORT=50051\n",
+			address: "10.0.0.1:50051",
+		},
+		{
+			name:    "default port",
+			file:    "sequencer-1.env",
+			content: "OP_CONDUCTOR_RAFT_SERVER_ID=sequencer-1\nOP_CONDUCTOR_CONSENSUS_ADDR=sequencer-1\n",
+			address: "sequencer-1:50050",
+		},
+		{
+			name: "flags override env",
+			file: "sequencer-1.args",
+			content: `OP_CONDUCTOR_RAFT_SERVER_ID=sequencer-1
+OP_CONDUCTOR_CONSENSUS_ADVERTISED=old:50050
+--consensus.advertised=sequencer-1:50050 \
+--raft.server.id sequencer-1 \
+--healthcheck.interval=1
+`,
+			address: "sequencer-1:50050",
+		},
+		{
+			name:    "conductor flags json",
+			file:    "conductor-flags.json",
+			content: `{"raft.server.id": "sequencer-1", "consensus.advertised": "sequencer-1:50050", "consensus.port": "50050"}`,
+			address: "sequencer-1:50050",
+		},
+		{
+			name:    "missing server id",
+			file:    "sequencer-1.env",
+			content: "OP_CONDUCTOR_CONSENSUS_ADVERTISED=sequencer-1:50050\n",
+			err:     "--raft.server.id (OP_CONDUCTOR_RAFT_SERVER_ID) is not set",
+		},
+		{
+			name:    "unreachable listen address",
+			file:    "sequencer-1.env",
+			content: "OP_CONDUCTOR_RAFT_SERVER_ID=sequencer-1\nOP_CONDUCTOR_CONSENSUS_ADDR=0.0.0.0\n",
+			err:     "which other nodes cannot reach",
+		},
+		{
+			name:    "malformed line",
+			file:    "sequencer-1.env",
+			content: "OP_CONDUCTOR_RAFT_SERVER_ID\n",
+			err:     "line 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := ImportConductorFile(writeConductorFile(t, tt.file, tt.content))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to import: %v", err)
+			}
+			if node.ServerID != "sequencer-1" || node.Address != tt.address || node.RPC != tt.rpc {
+				t.Fatalf("Unexpected node: %+v", node)
+			}
+		})
+	}
+}
+
+func TestNewConfigFromConductorEnv(t *testing.T) {
+	var paths []string
+	for _, id := range []string{"sequencer-1", "sequencer-2", "sequencer-3"} {
+		paths = append(paths, writeConductorFile(t, id+".env",
+			"OP_CONDUCTOR_RAFT_SERVER_ID="+id+"\nOP_CONDUCTOR_CONSENSUS_ADVERTISED="+id+":50050\n"))
+	}
+
+	cfg, err := NewConfig(newTestContext(t,
+		"--from-conductor-env", strings.Join(paths, ","),
+		"--non-voters", "sequencer-3",
+		"--initial-leader", "sequencer-1",
+	), log.New())
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	if len(cfg.Nodes) != 3 || cfg.Nodes[1].ServerID != "sequencer-2" || cfg.Nodes[1].Address != "sequencer-2:50050" {
+		t.Fatalf("Unexpected nodes: %+v", cfg.Nodes)
+	}
+
+	_, err = NewConfig(newTestContext(t,
+		"--from-conductor-env", strings.Join(paths, ","),
+		"--nodes", "sequencer-1:50050",
+		"--initial-leader", "sequencer-1",
+	), log.New())
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Fatalf("Expected --from-conductor-env and --nodes to conflict, got: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/hashicorp/raft"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ethereum-optimism/optimism/op-conductor/consensus"
+	"github.com/golem-base/op-conductor-init/pkg/flags"
+	"github.com/golem-base/op-conductor-init/pkg/live"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+// liveTimeout bounds the queries of the --from-live conductor
+const liveTimeout = 10 * time.Second
+
+// applyLive reproduces the membership of a running cluster.
+// The current leader becomes the initial leader unless one is set explicitly.
+func (c *Config) applyLive(ctx *cli.Context, log log.Logger) error {
+	membership, err := live.Capture(ctx.Context, ctx.String(flags.FromLiveFlag.Name), liveTimeout)
+	if err != nil {
+		return err
+	}
+
+	c.Nodes = make([]NodeConfig, 0, len(membership.Servers))
+	for _, server := range membership.Servers {
+		node := NodeConfig{ServerID: server.ID, Address: server.Addr}
+		switch server.Suffrage {
+		case consensus.Voter:
+			node.Suffrage = raft.Voter
+		case consensus.Nonvoter:
+			node.Suffrage = raft.Nonvoter
+		default:
+			return fmt.Errorf("live member %s has unknown suffrage %d", server.ID, server.Suffrage)
+		}
+		c.Nodes = append(c.Nodes, node)
+	}
+	log.Info("Captured live cluster membership",
+		"endpoint", membership.Endpoint,
+		"servers", len(membership.Servers),
+		"version", membership.Version,
+		"leader", membership.Leader,
+	)
+
+	if ctx.IsSet(flags.InitialLeaderFlag.Name) || ctx.Bool(flags.NoInitialLeaderFlag.Name) {
+		return nil
+	}
+	switch {
+	case membership.LeaderErr != nil:
+		return fmt.Errorf("failed to query the live leader from %s, set --%s or --%s: %w", membership.Endpoint,
+			flags.InitialLeaderFlag.Name, flags.NoInitialLeaderFlag.Name, membership.LeaderErr)
+	case membership.Leader == "":
+		return fmt.Errorf("the live cluster has no leader, set --%s or --%s",
+			flags.InitialLeaderFlag.Name, flags.NoInitialLeaderFlag.Name)
+	}
+	c.InitialLeader = membership.Leader
+	return nil
+}
+
+// bumpedTerm returns the term one above the current term of a node's state.
+// The conductor RPC does not expose the Raft term, so it is read from the stable store.
+func bumpedTerm(stateDir string) (uint64, error) {
+	stable, err := store.ReadStableStore(filepath.Join(stateDir, store.StableStoreFile))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read current term from %s: %w", stateDir, err)
+	}
+	return stable.CurrentTerm + 1, nil
+}
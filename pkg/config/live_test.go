@@ -0,0 +1,74 @@
+package config
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/hashicorp/raft"
+
+	"github.com/ethereum-optimism/optimism/op-conductor/consensus"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+// stubConductorAPI serves a fixed membership led by the second server
+type stubConductorAPI struct{}
+
+var liveServers = []consensus.ServerInfo{
+	{ID: "sequencer-1", Addr: "sequencer-1:50050", Suffrage: consensus.Voter},
+	{ID: "sequencer-2", Addr: "sequencer-2:50050", Suffrage: consensus.Voter},
+	{ID: "sequencer-3", Addr: "sequencer-3:50050", Suffrage: consensus.Nonvoter},
+}
+
+func (stubConductorAPI) ClusterMembership(ctx context.Context) (*consensus.ClusterMembership, error) {
+	return &consensus.ClusterMembership{Servers: liveServers, Version: 3}, nil
+}
+
+func (stubConductorAPI) LeaderWithID(ctx context.Context) (*consensus.ServerInfo, error) {
+	return &liveServers[1], nil
+}
+
+func TestNewConfigFromLive(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("conductor", stubConductorAPI{}); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	defer server.Stop()
+
+	stateDir := t.TempDir()
+	if err := store.CreateStableStore(stateDir, "sequencer-1", 41, false); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewConfig(newTestContext(t, "--from-live", httpServer.URL, "--bump-term-from", stateDir), log.New())
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	if len(cfg.Nodes) != 3 || cfg.Nodes[2].Suffrage != raft.Nonvoter || cfg.Nodes[0].Address != "sequencer-1:50050" {
+		t.Fatalf("Unexpected nodes: %+v", cfg.Nodes)
+	}
+	if cfg.InitialLeader != "sequencer-2" {
+		t.Fatalf("Expected the live leader sequencer-2, got %q", cfg.InitialLeader)
+	}
+	if cfg.InitialTerm != 42 {
+		t.Fatalf("Expected initial term 42, got %d", cfg.InitialTerm)
+	}
+
+	cfg, err = NewConfig(newTestContext(t, "--from-live", httpServer.URL, "--initial-leader", "sequencer-1"), log.New())
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	if cfg.InitialLeader != "sequencer-1" || cfg.InitialTerm != 1 {
+		t.Fatalf("Expected the explicit leader and default term, got %q at term %d", cfg.InitialLeader, cfg.InitialTerm)
+	}
+
+	_, err = NewConfig(newTestContext(t, "--from-live", httpServer.URL, "--bump-term-from", stateDir, "--initial-term", "5"), log.New())
+	if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Fatalf("Expected --bump-term-from and --initial-term to conflict, got: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/raft"
+	"gopkg.in/yaml.v3"
+)
+
+// SpecVersion is the cluster spec schema version understood by this tool
+const SpecVersion = 1
+
+// ClusterSpec is a declarative, versioned description of a Raft cluster
+type ClusterSpec struct {
+	Version       int        `json:"version" yaml:"version" toml:"version"`
+	Network       string     `json:"network,omitempty" yaml:"network,omitempty" toml:"network,omitempty"`
+	InitialLeader string     `json:"initialLeader" yaml:"initialLeader" toml:"initialLeader"`
+	InitialTerm   uint64     `json:"initialTerm,omitempty" yaml:"initialTerm,omitempty" toml:"initialTerm,omitempty"`
+	Nodes         []NodeSpec `json:"nodes" yaml:"nodes" toml:"nodes"`
+}
+
+// NodeSpec describes a single node of a ClusterSpec
+type NodeSpec struct {
+	ServerID string            `json:"serverId" yaml:"serverId" toml:"serverId"`
+	Address  string            `json:"address" yaml:"address" toml:"address"`
+	Suffrage string            `json:"suffrage,omitempty" yaml:"suffrage,omitempty" toml:"suffrage,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty" toml:"labels,omitempty"`
+	RPC      string            `json:"rpc,omitempty" yaml:"rpc,omitempty" toml:"rpc,omitempty"`
+}
+
+// LoadSpec reads a cluster spec from a YAML, TOML or JSON file.
+// The format is selected by the file extension and unknown fields are rejected.
+func LoadSpec(path string) (*ClusterSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster spec: %w", err)
+	}
+
+	spec := &ClusterSpec{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(spec); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML cluster spec: %w", err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse TOML cluster spec: %w", err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("failed to parse TOML cluster spec: unknown field %q", undecoded[0].String())
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(spec); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON cluster spec: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported cluster spec format %q (expected .yaml, .yml, .toml or .json)", ext)
+	}
+
+	return spec, nil
+}
+
+// Validate checks the spec against the schema and reports every problem found
+func (s *ClusterSpec) Validate() error {
+	var result *multierror.Error
+
+	if s.Version != SpecVersion {
+		result = multierror.Append(result, fmt.Errorf("version: unsupported spec version %d (expected %d)", s.Version, SpecVersion))
+	}
+	if len(s.Nodes) == 0 {
+		result = multierror.Append(result, fmt.Errorf("nodes: at least one node is required"))
+	}
+
+	for i, node := range s.Nodes {
+		if strings.TrimSpace(node.ServerID) == "" {
+			result = multierror.Append(result, fmt.Errorf("nodes[%d].serverId: must not be empty", i))
+		}
+		if strings.TrimSpace(node.Address) == "" {
+			result = multierror.Append(result, fmt.Errorf("nodes[%d].address: must not be empty", i))
+		}
+		if _, err := ParseSuffrage(node.Suffrage); err != nil {
+			result = multierror.Append(result, fmt.Errorf("nodes[%d].suffrage: %w", i, err))
+		}
+		for key := range node.Labels {
+			if key == "" {
+				result = multierror.Append(result, fmt.Errorf("nodes[%d].labels: label keys must not be empty", i))
+			}
+		}
+	}
+
+	leader := strings.TrimSpace(s.InitialLeader)
+	if leader == "" {
+		result = multierror.Append(result, fmt.Errorf("initialLeader: must not be empty"))
+	} else if leader != NoInitialLeader && leader != AutoInitialLeader {
+		if node := s.findNode(leader); node == nil {
+			result = multierror.Append(result, fmt.Errorf("initialLeader: %s not found in nodes", leader))
+		} else if suffrage, err := ParseSuffrage(node.Suffrage); err == nil && suffrage != raft.Voter {
+			result = multierror.Append(result, fmt.Errorf("initialLeader: %s is a %s, only voters can lead", leader, node.Suffrage))
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+// NodeConfigs converts the spec nodes into NodeConfig entries, preserving order
+func (s *ClusterSpec) NodeConfigs() ([]NodeConfig, error) {
+	nodes := make([]NodeConfig, len(s.Nodes))
+	for i, node := range s.Nodes {
+		suffrage, err := ParseSuffrage(node.Suffrage)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", node.ServerID, err)
+		}
+		nodes[i] = NodeConfig{
+			ServerID: strings.TrimSpace(node.ServerID),
+			Address:  strings.TrimSpace(node.Address),
+			Suffrage: suffrage,
+			Labels:   node.Labels,
+			RPC:      strings.TrimSpace(node.RPC),
+		}
+	}
+	return nodes, nil
+}
+
+// findNode returns the node with the given server ID, compared the way NodeConfigs trims it
+func (s *ClusterSpec) findNode(serverID string) *NodeSpec {
+	for i := range s.Nodes {
+		if strings.TrimSpace(s.Nodes[i].ServerID) == serverID {
+			return &s.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// ParseSuffrage converts a suffrage name into a raft.ServerSuffrage.
+// An empty name defaults to raft.Voter.
+func ParseSuffrage(name string) (raft.ServerSuffrage, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "voter":
+		return raft.Voter, nil
+	case "nonvoter", "non-voter":
+		return raft.Nonvoter, nil
+	case "staging":
+		return raft.Staging, nil
+	default:
+		return raft.Voter, fmt.Errorf("unknown suffrage %q (expected voter, nonvoter or staging)", name)
+	}
+}
+
+// SortedLabels returns the labels as key=value pairs in a stable order
+func SortedLabels(labels map[string]string) []string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
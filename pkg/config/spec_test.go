@@ -0,0 +1,162 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/raft"
+)
+
+const yamlSpec = `version: 1
+network: op-sepolia
+initialLeader: sequencer-1
+initialTerm: 2
+nodes:
+  - serverId: sequencer-1
+    address: sequencer-1:50050
+    labels:
+      region: eu
+  - serverId: sequencer-2
+    address: sequencer-2:50050
+  - serverId: sequencer-3
+    address: sequencer-3:50050
+    suffrage: nonvoter
+`
+
+const tomlSpec = `version = 1
+network = "op-sepolia"
+initialLeader = "sequencer-1"
+initialTerm = 2
+
+[[nodes]]
+serverId = "sequencer-1"
+address = "sequencer-1:50050"
+labels = { region = "eu" }
+
+[[nodes]]
+serverId = "sequencer-2"
+address = "sequencer-2:50050"
+
+[[nodes]]
+serverId = "sequencer-3"
+address = "sequencer-3:50050"
+suffrage = "nonvoter"
+`
+
+const jsonSpec = `{
+  "version": 1,
+  "network": "op-sepolia",
+  "initialLeader": "sequencer-1",
+  "initialTerm": 2,
+  "nodes": [
+    {"serverId": "sequencer-1", "address": "sequencer-1:50050", "labels": {"region": "eu"}},
+    {"serverId": "sequencer-2", "address": "sequencer-2:50050"},
+    {"serverId": "sequencer-3", "address": "sequencer-3:50050", "suffrage": "nonvoter"}
+  ]
+}`
+
+func writeSpec(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSpecFormats(t *testing.T) {
+	for name, content := range map[string]string{
+		"cluster.yaml": yamlSpec,
+		"cluster.toml": tomlSpec,
+		"cluster.json": jsonSpec,
+	} {
+		t.Run(name, func(t *testing.T) {
+			spec, err := LoadSpec(writeSpec(t, name, content))
+			if err != nil {
+				t.Fatalf("Failed to load spec: %v", err)
+			}
+			if err := spec.Validate(); err != nil {
+				t.Fatalf("Expected valid spec, got: %v", err)
+			}
+
+			nodes, err := spec.NodeConfigs()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(nodes) != 3 {
+				t.Fatalf("Expected 3 nodes, got %d", len(nodes))
+			}
+			if spec.InitialLeader != "sequencer-1" || spec.InitialTerm != 2 || spec.Network != "op-sepolia" {
+				t.Fatalf("Unexpected cluster settings: %+v", spec)
+			}
+			if nodes[0].Labels["region"] != "eu" {
+				t.Fatalf("Expected label region=eu, got %v", nodes[0].Labels)
+			}
+			if nodes[2].Suffrage != raft.Nonvoter {
+				t.Fatalf("Expected sequencer-3 to be a nonvoter, got %v", nodes[2].Suffrage)
+			}
+		})
+	}
+}
+
+func TestLoadSpecRejectsUnknownFields(t *testing.T) {
+	path := writeSpec(t, "cluster.yaml", yamlSpec+"unexpected: true\n")
+	if _, err := LoadSpec(path); err == nil {
+		t.Fatal("Expected unknown field to be rejected")
+	}
+}
+
+func TestSpecValidateReportsAllErrors(t *testing.T) {
+	spec := &ClusterSpec{
+		Version:       2,
+		InitialLeader: "sequencer-9",
+		Nodes: []NodeSpec{
+			{ServerID: "", Address: "sequencer-1:50050"},
+			{ServerID: "sequencer-2", Address: "", Suffrage: "observer"},
+		},
+	}
+
+	err := spec.Validate()
+	if err == nil {
+		t.Fatal("Expected validation to fail")
+	}
+
+	for _, want := range []string{
+		"version",
+		"nodes[0].serverId",
+		"nodes[1].address",
+		"nodes[1].suffrage",
+		"initialLeader",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestSpecValidateInitialLeader(t *testing.T) {
+	spec := &ClusterSpec{
+		Version:       SpecVersion,
+		InitialLeader: "sequencer-1",
+		Nodes: []NodeSpec{
+			{ServerID: " sequencer-1", Address: "sequencer-1:50050"},
+			{ServerID: "sequencer-2", Address: "sequencer-2:50050", Suffrage: "nonvoter"},
+		},
+	}
+	if err := spec.Validate(); err != nil {
+		t.Fatalf("Expected the leader to match the trimmed server ID, got: %v", err)
+	}
+
+	spec.InitialLeader = "sequencer-2"
+	if err := spec.Validate(); err == nil || !strings.Contains(err.Error(), "only voters can lead") {
+		t.Fatalf("Expected a non-voting initial leader to be rejected, got: %v", err)
+	}
+
+	spec.InitialLeader = " sequencer-1"
+	spec.Nodes[0].ServerID = "sequencer-1x"
+	if err := spec.Validate(); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected an unknown initial leader to be rejected, got: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/raft"
+)
+
+// templateVariable matches a {name} placeholder in a node template
+var templateVariable = regexp.MustCompile(`\{[^{}]*\}`)
+
+// NodeTemplate describes a StatefulSet-style cluster whose nodes only differ by their ordinal
+type NodeTemplate struct {
+	Replicas int
+	// Offset is the ordinal of the first replica
+	Offset    int
+	Namespace string
+
+	IDTemplate      string
+	AddressTemplate string
+	// RPCTemplate is optional, nodes get no op-node RPC when it is empty
+	RPCTemplate string
+
+	// AddressOverrides maps server IDs to addresses that replace the templated ones
+	AddressOverrides map[string]string
+}
+
+// Expand renders the templates for every replica.
+// {i} is replaced with the replica's ordinal and {ns} with the namespace.
+func (t *NodeTemplate) Expand() ([]NodeConfig, error) {
+	if t.Replicas < 1 {
+		return nil, fmt.Errorf("replicas must be at least 1, got %d", t.Replicas)
+	}
+	if t.Offset < 0 {
+		return nil, fmt.Errorf("ordinal offset must not be negative, got %d", t.Offset)
+	}
+	if !strings.Contains(t.IDTemplate, "{i}") {
+		return nil, fmt.Errorf("id template %q must contain {i}", t.IDTemplate)
+	}
+	for _, tmpl := range []string{t.IDTemplate, t.AddressTemplate, t.RPCTemplate} {
+		if err := t.checkTemplate(tmpl); err != nil {
+			return nil, err
+		}
+	}
+
+	nodes := make([]NodeConfig, t.Replicas)
+	for i := range nodes {
+		ordinal := t.Offset + i
+		nodes[i] = NodeConfig{
+			ServerID: t.render(t.IDTemplate, ordinal),
+			Address:  t.render(t.AddressTemplate, ordinal),
+			Suffrage: raft.Voter,
+			RPC:      t.render(t.RPCTemplate, ordinal),
+		}
+	}
+
+	ids := make([]string, 0, len(t.AddressOverrides))
+	for id := range t.AddressOverrides {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		address := t.AddressOverrides[id]
+		found := false
+		for i := range nodes {
+			if nodes[i].ServerID == id {
+				nodes[i].Address = address
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("address override for %s does not match any templated server ID", id)
+		}
+	}
+
+	return nodes, nil
+}
+
+// checkTemplate rejects unknown variables, and {ns} without a namespace to substitute
+func (t *NodeTemplate) checkTemplate(tmpl string) error {
+	for _, variable := range templateVariable.FindAllString(tmpl, -1) {
+		switch variable {
+		case "{i}":
+		case "{ns}":
+			if t.Namespace == "" {
+				return fmt.Errorf("template %q uses {ns} but no namespace is set", tmpl)
+			}
+		default:
+			return fmt.Errorf("template %q uses unknown variable %s (expected {i} or {ns})", tmpl, variable)
+		}
+	}
+	return nil
+}
+
+func (t *NodeTemplate) render(tmpl string, ordinal int) string {
+	return strings.NewReplacer("{i}", strconv.Itoa(ordinal), "{ns}", t.Namespace).Replace(tmpl)
+}
+
+// parseOverrides parses comma-separated server-id=address pairs
+func parseOverrides(overridesFlag string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	if strings.TrimSpace(overridesFlag) == "" {
+		return overrides, nil
+	}
+
+	for _, pair := range strings.Split(overridesFlag, ",") {
+		id, address, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		id, address = strings.TrimSpace(id), strings.TrimSpace(address)
+		if !ok || id == "" || address == "" {
+			return nil, fmt.Errorf("invalid address override %q: expected server-id=address", pair)
+		}
+		if _, ok := overrides[id]; ok {
+			return nil, fmt.Errorf("duplicate address override for %s", id)
+		}
+		overrides[id] = address
+	}
+
+	return overrides, nil
+}
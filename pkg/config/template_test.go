@@ -0,0 +1,80 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNodeTemplateExpand(t *testing.T) {
+	template := &NodeTemplate{
+		Replicas:         3,
+		Offset:           1,
+		Namespace:        "mainnet",
+		IDTemplate:       "sequencer-{i}",
+		AddressTemplate:  "sequencer-{i}.seq.{ns}.svc.cluster.local:50050",
+		RPCTemplate:      "http://op-node-{i}.{ns}:9545",
+		AddressOverrides: map[string]string{"sequencer-2": "10.0.0.2:50050"},
+	}
+
+	nodes, err := template.Expand()
+	if err != nil {
+		t.Fatalf("Failed to expand templates: %v", err)
+	}
+
+	expected := []NodeConfig{
+		{ServerID: "sequencer-1", Address: "sequencer-1.seq.mainnet.svc.cluster.local:50050", RPC: "http://op-node-1.mainnet:9545"},
+		{ServerID: "sequencer-2", Address: "10.0.0.2:50050", RPC: "http://op-node-2.mainnet:9545"},
+		{ServerID: "sequencer-3", Address: "sequencer-3.seq.mainnet.svc.cluster.local:50050", RPC: "http://op-node-3.mainnet:9545"},
+	}
+	if len(nodes) != len(expected) {
+		t.Fatalf("Expected %d nodes, got %d", len(expected), len(nodes))
+	}
+	for i, node := range nodes {
+		if node.ServerID != expected[i].ServerID || node.Address != expected[i].Address || node.RPC != expected[i].RPC {
+			t.Fatalf("Node %d: expected %+v, got %+v", i, expected[i], node)
+		}
+	}
+}
+
+func TestNodeTemplateExpandErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template NodeTemplate
+		err      string
+	}{
+		{
+			name:     "no replicas",
+			template: NodeTemplate{IDTemplate: "sequencer-{i}", AddressTemplate: "sequencer-{i}:50050"},
+			err:      "replicas must be at least 1",
+		},
+		{
+			name:     "constant id",
+			template: NodeTemplate{Replicas: 3, IDTemplate: "sequencer", AddressTemplate: "sequencer-{i}:50050"},
+			err:      "must contain {i}",
+		},
+		{
+			name:     "unknown variable",
+			template: NodeTemplate{Replicas: 3, IDTemplate: "sequencer-{i}", AddressTemplate: "sequencer-{index}:50050"},
+			err:      "unknown variable {index}",
+		},
+		{
+			name:     "missing namespace",
+			template: NodeTemplate{Replicas: 3, IDTemplate: "sequencer-{i}", AddressTemplate: "sequencer-{i}.{ns}:50050"},
+			err:      "no namespace is set",
+		},
+		{
+			name: "unknown override",
+			template: NodeTemplate{Replicas: 3, IDTemplate: "sequencer-{i}", AddressTemplate: "sequencer-{i}:50050",
+				AddressOverrides: map[string]string{"sequencer-3": "10.0.0.3:50050"}},
+			err: "sequencer-3 does not match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.template.Expand(); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Expected error containing %q, got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// ValidateNodes checks the server IDs and addresses of all nodes and reports every problem found
+func ValidateNodes(nodes []NodeConfig) error {
+	return validateNodes(nodes, nil)
+}
+
+// ValidateChangedNodes checks the server IDs and addresses of the nodes in changed, and that no two
+// nodes share a server ID or address. Other nodes are existing members and are accepted as they
+// are, so state written under older rules can still be changed.
+func ValidateChangedNodes(nodes []NodeConfig, changed map[string]bool) error {
+	if changed == nil {
+		changed = map[string]bool{}
+	}
+	return validateNodes(nodes, changed)
+}
+
+// validateNodes checks every node, or only the nodes in changed when it is not nil
+func validateNodes(nodes []NodeConfig, changed map[string]bool) error {
+	var result *multierror.Error
+
+	if len(nodes) == 0 {
+		result = multierror.Append(result, fmt.Errorf("at least one node is required"))
+	}
+
+	ids := make(map[string]int)
+	addresses := make(map[string]int)
+	for i, node := range nodes {
+		check := changed == nil || changed[node.ServerID]
+
+		if check && node.ServerID == "" {
+			result = multierror.Append(result, fmt.Errorf("node %d: server ID must not be empty", i+1))
+		} else if err := validateServerID(node.ServerID); check && err != nil {
+			result = multierror.Append(result, fmt.Errorf("node %d: %w", i+1, err))
+		} else if first, ok := ids[node.ServerID]; ok {
+			result = multierror.Append(result, fmt.Errorf("node %d: duplicate server ID %s (also used by node %d)", i+1, node.ServerID, first+1))
+		} else {
+			ids[node.ServerID] = i
+		}
+
+		// Addresses of unchecked nodes only take part in the collision check, as written when unparsable
+		key := node.Address
+		host, port, err := splitAddress(node.Address)
+		switch {
+		case err == nil:
+			key = addressKey(host, port)
+		case check:
+			result = multierror.Append(result, fmt.Errorf("node %d (%s): %w", i+1, node.ServerID, err))
+			continue
+		}
+		if first, ok := addresses[key]; ok {
+			result = multierror.Append(result, fmt.Errorf("node %d (%s): duplicate address %s (also used by %s)",
+				i+1, node.ServerID, node.Address, nodes[first].ServerID))
+		} else {
+			addresses[key] = i
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+// addressKey compares IP addresses in canonical form, so different spellings of the same IPv6 address collide
+func addressKey(host, port string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		return net.JoinHostPort(ip.String(), port)
+	}
+	return net.JoinHostPort(strings.ToLower(host), port)
+}
+
+// validateServerID checks that a server ID can be used as a directory name below the output directory
+func validateServerID(id string) error {
+	if strings.ContainsAny(id, `/\`) || id == "." || id == ".." || filepath.Clean(id) != id {
+		return fmt.Errorf("invalid server ID %q: must be usable as a directory name, without path separators, . or ..", id)
+	}
+	return nil
+}
+
+// splitAddress splits a Raft consensus address into host and port and checks both
+func splitAddress(address string) (string, string, error) {
+	if address == "" {
+		return "", "", fmt.Errorf("address must not be empty")
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		if strings.Count(address, ":") > 1 && !strings.HasPrefix(address, "[") {
+			return "", "", fmt.Errorf("invalid address %q: IPv6 addresses must be written as [addr]:port", address)
+		}
+		return "", "", fmt.Errorf("invalid address %q: expected host:port", address)
+	}
+
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		return "", "", fmt.Errorf("invalid address %q: port must be a number between 1 and 65535", address)
+	}
+
+	switch {
+	case host == "":
+		return "", "", fmt.Errorf("invalid address %q: host must not be empty", address)
+	case strings.Contains(host, ":"):
+		if net.ParseIP(host) == nil {
+			return "", "", fmt.Errorf("invalid address %q: malformed IPv6 literal %s", address, host)
+		}
+	case net.ParseIP(host) == nil && !validHostname(host):
+		return "", "", fmt.Errorf("invalid address %q: malformed hostname %s", address, host)
+	}
+
+	return host, port, nil
+}
+
+// validHostname reports whether host is a syntactically valid DNS name
+func validHostname(host string) bool {
+	if len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
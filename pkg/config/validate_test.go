@@ -0,0 +1,58 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateNodesReportsAllErrors(t *testing.T) {
+	nodes, err := parseNodes(
+		"sequencer-1:50050,sequencer-2,sequencer-1:50050,::1:50050,[::1:50050,,[::1]:50050,[0:0::1]:50050,bad_host-:1,host:99999",
+		"sequencer-1,sequencer-2,sequencer-3,sequencer-4,sequencer-5,,sequencer-7,sequencer-8,sequencer-1,sequencer-10",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ValidateNodes(nodes)
+	if err == nil {
+		t.Fatal("Expected validation to fail")
+	}
+
+	for _, want := range []string{
+		`node 2 (sequencer-2): invalid address "sequencer-2": expected host:port`,
+		"node 3 (sequencer-3): duplicate address sequencer-1:50050 (also used by sequencer-1)",
+		`node 4 (sequencer-4): invalid address "::1:50050": IPv6 addresses must be written as [addr]:port`,
+		`node 5 (sequencer-5): invalid address "[::1:50050"`,
+		"node 6: server ID must not be empty",
+		"node 6 (): address must not be empty",
+		"node 8 (sequencer-8): duplicate address [0:0::1]:50050 (also used by sequencer-7)",
+		"node 9: duplicate server ID sequencer-1 (also used by node 1)",
+		`node 9 (sequencer-1): invalid address "bad_host-:1": malformed hostname bad_host-`,
+		`node 10 (sequencer-10): invalid address "host:99999": port must be a number between 1 and 65535`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateNodesRejectsUnsafeServerIDs(t *testing.T) {
+	for _, id := range []string{"../x", "a/b", ".", "..", `a\b`, "/abs"} {
+		nodes := []NodeConfig{{ServerID: id, Address: "sequencer-1:50050"}}
+		if err := ValidateNodes(nodes); err == nil || !strings.Contains(err.Error(), "invalid server ID") {
+			t.Errorf("Expected server ID %q to be rejected, got: %v", id, err)
+		}
+	}
+}
+
+func TestValidateNodesAcceptsValidAddresses(t *testing.T) {
+	nodes := []NodeConfig{
+		{ServerID: "sequencer-1", Address: "sequencer-1.sequencer.svc.cluster.local:50050"},
+		{ServerID: "sequencer-2", Address: "10.0.0.2:50050"},
+		{ServerID: "sequencer-3", Address: "[2001:db8::3]:50050"},
+	}
+	if err := ValidateNodes(nodes); err != nil {
+		t.Fatalf("Expected nodes to be valid, got: %v", err)
+	}
+}
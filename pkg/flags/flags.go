@@ -1,6 +1,8 @@
 package flags
 
 import (
+	"time"
+
 	"github.com/urfave/cli/v2"
 
 	opservice "github.com/ethereum-optimism/optimism/op-service"
@@ -10,17 +12,80 @@ import (
 const EnvVarPrefix = "OP_CONDUCTOR_INIT"
 
 var (
+	SpecFlag = &cli.StringFlag{
+		Name:    "spec",
+		Usage:   "Path to a declarative cluster spec file (.yaml, .yml, .toml or .json); replaces --nodes and --server-ids",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "SPEC"),
+	}
 	NodesFlag = &cli.StringFlag{
-		Name:     "nodes",
-		Usage:    "Comma-separated list of node addresses (e.g., sequencer-1:50050,sequencer-2:50050,sequencer-3:50050)",
-		EnvVars:  opservice.PrefixEnvVar(EnvVarPrefix, "NODES"),
-		Required: true,
+		Name:    "nodes",
+		Usage:   "Comma-separated list of node addresses (e.g., sequencer-1:50050,sequencer-2:50050,sequencer-3:50050)",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "NODES"),
 	}
 	ServerIDsFlag = &cli.StringFlag{
-		Name:     "server-ids",
-		Usage:    "Comma-separated list of server IDs (e.g., sequencer-1,sequencer-2,sequencer-3)",
-		EnvVars:  opservice.PrefixEnvVar(EnvVarPrefix, "SERVER_IDS"),
-		Required: true,
+		Name:    "server-ids",
+		Usage:   "Comma-separated list of server IDs (e.g., sequencer-1,sequencer-2,sequencer-3)",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "SERVER_IDS"),
+	}
+	ReplicasFlag = &cli.IntFlag{
+		Name:    "replicas",
+		Usage:   "Number of nodes to expand from --id-template and --address-template; replaces --nodes and --server-ids",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "REPLICAS"),
+	}
+	FromConductorEnvFlag = &cli.StringFlag{
+		Name:    "from-conductor-env",
+		Usage:   "Comma-separated list of op-conductor env or flag files, one per node, to derive the nodes from; replaces --nodes and --server-ids",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "FROM_CONDUCTOR_ENV"),
+	}
+	FromLiveFlag = &cli.StringFlag{
+		Name:    "from-live",
+		Usage:   "op-conductor RPC endpoint of a running cluster whose membership and leader are reproduced; replaces --nodes and --server-ids",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "FROM_LIVE"),
+	}
+	IDTemplateFlag = &cli.StringFlag{
+		Name:    "id-template",
+		Usage:   "Server ID template for --replicas, supports {i} and {ns} (e.g., sequencer-{i})",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ID_TEMPLATE"),
+	}
+	AddressTemplateFlag = &cli.StringFlag{
+		Name:    "address-template",
+		Usage:   "Address template for --replicas, supports {i} and {ns} (e.g., sequencer-{i}.seq.{ns}.svc.cluster.local:50050)",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ADDRESS_TEMPLATE"),
+	}
+	RPCTemplateFlag = &cli.StringFlag{
+		Name:    "rpc-template",
+		Usage:   "op-node RPC endpoint template for --replicas, supports {i} and {ns}",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "RPC_TEMPLATE"),
+	}
+	NamespaceFlag = &cli.StringFlag{
+		Name:    "namespace",
+		Usage:   "Kubernetes namespace: the value of {ns} in node templates and of the manifests written by --emit k8s",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "NAMESPACE"),
+	}
+	OrdinalOffsetFlag = &cli.IntFlag{
+		Name:    "ordinal-offset",
+		Usage:   "Ordinal of the first replica, {i} counts up from it",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ORDINAL_OFFSET"),
+	}
+	AddressOverridesFlag = &cli.StringFlag{
+		Name:    "address-overrides",
+		Usage:   "Comma-separated list of server-id=address pairs replacing templated addresses",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ADDRESS_OVERRIDES"),
+	}
+	NodeRPCsFlag = &cli.StringFlag{
+		Name:    "node-rpcs",
+		Usage:   "Comma-separated list of op-node RPC endpoints in node order, used by --initial-leader auto",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "NODE_RPCS"),
+	}
+	NonVotersFlag = &cli.StringFlag{
+		Name:    "non-voters",
+		Usage:   "Comma-separated list of server IDs to add as non-voting members (never count toward quorum)",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "NON_VOTERS"),
+	}
+	StagingFlag = &cli.StringFlag{
+		Name:    "staging",
+		Usage:   "Comma-separated list of server IDs to add with staging suffrage",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "STAGING"),
 	}
 	OutputDirFlag = &cli.StringFlag{
 		Name:    "output-dir",
@@ -29,10 +94,14 @@ var (
 		Value:   "./raft-state",
 	}
 	InitialLeaderFlag = &cli.StringFlag{
-		Name:     "initial-leader",
-		Usage:    "Server ID of the initial leader",
-		EnvVars:  opservice.PrefixEnvVar(EnvVarPrefix, "INITIAL_LEADER"),
-		Required: true,
+		Name:    "initial-leader",
+		Usage:   "Server ID of the initial leader (required unless set in --spec); none leaves the leader to the first election, auto picks the voter with the highest unsafe head",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "INITIAL_LEADER"),
+	}
+	NoInitialLeaderFlag = &cli.BoolFlag{
+		Name:    "no-initial-leader",
+		Usage:   "Do not record a vote for any node, so the leader is decided by a normal election on first boot",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "NO_INITIAL_LEADER"),
 	}
 	InitialTermFlag = &cli.Uint64Flag{
 		Name:    "initial-term",
@@ -40,6 +109,22 @@ var (
 		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "INITIAL_TERM"),
 		Value:   1,
 	}
+	InitialIndexFlag = &cli.Uint64Flag{
+		Name:    "initial-index",
+		Usage:   "Log index of the configuration entry; above 1 the log is anchored by a snapshot just below it (--mode log only)",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "INITIAL_INDEX"),
+		Value:   1,
+	}
+	AboveStateFlag = &cli.StringFlag{
+		Name:    "above-state",
+		Usage:   "State directory of an old node; the generated state continues strictly above its last log index and term",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ABOVE_STATE"),
+	}
+	BumpTermFromFlag = &cli.StringFlag{
+		Name:    "bump-term-from",
+		Usage:   "State directory of a cluster member; the initial term is set one above its current term",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "BUMP_TERM_FROM"),
+	}
 	NetworkFlag = &cli.StringFlag{
 		Name:    "network",
 		Usage:   "Network name for configuration (e.g., base-mainnet, op-mainnet)",
@@ -47,10 +132,103 @@ var (
 	}
 	ForceFlag = &cli.BoolFlag{
 		Name:    "force",
-		Usage:   "Force overwrite existing state files",
+		Usage:   "Replace existing state files, keeping the previous output as a timestamped backup",
 		Value:   false,
 		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "FORCE"),
 	}
+	SelfFlag = &cli.StringFlag{
+		Name:    "self",
+		Usage:   "Server ID of the calling node: only generate its state, into --state-dir, and only when that holds no Raft state yet",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "SELF"),
+	}
+	EnsureFlag = &cli.BoolFlag{
+		Name:    "ensure",
+		Usage:   "Succeed without changes when existing state matches the configuration, generate when there is none, refuse otherwise",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ENSURE"),
+	}
+	UnsafePayloadFlag = &cli.StringFlag{
+		Name:    "unsafe-payload",
+		Usage:   "Path to a JSON execution payload envelope to seed the consensus FSM with as the initial unsafe head",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "UNSAFE_PAYLOAD"),
+	}
+	UnsafePayloadRPCFlag = &cli.StringFlag{
+		Name:    "unsafe-payload-rpc",
+		Usage:   "Execution client (op-geth) RPC endpoint to fetch the initial unsafe head payload from; op-node endpoints are rejected",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "UNSAFE_PAYLOAD_RPC"),
+	}
+	UnsafePayloadBlockFlag = &cli.StringFlag{
+		Name:    "unsafe-payload-block",
+		Usage:   "Block to fetch from --unsafe-payload-rpc (latest, safe, finalized or a block number)",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "UNSAFE_PAYLOAD_BLOCK"),
+		Value:   "latest",
+	}
+	ModeFlag = &cli.StringFlag{
+		Name:    "mode",
+		Usage:   "Generation mode: 'log' writes the configuration as the log entry at --initial-index, 'snapshot' writes a FileSnapshotStore snapshot instead",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "MODE"),
+		Value:   "log",
+	}
+	BundleFlag = &cli.StringFlag{
+		Name:    "bundle",
+		Usage:   "Also write a per-node deployment bundle: 'tar.gz' or 'oci' (an OCI image layout tarball)",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "BUNDLE"),
+	}
+	ConductorStorageDirFlag = &cli.StringFlag{
+		Name:    "conductor-storage-dir",
+		Usage:   "op-conductor --raft.storage.dir written to the generated conductor.env of every node",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "CONDUCTOR_STORAGE_DIR"),
+	}
+	ConductorFlagsJSONFlag = &cli.BoolFlag{
+		Name:    "conductor-flags-json",
+		Usage:   "Also write the op-conductor flags of every node as conductor-flags.json",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "CONDUCTOR_FLAGS_JSON"),
+	}
+	EmitFlag = &cli.StringFlag{
+		Name:    "emit",
+		Usage:   "Also write deployment manifests: 'k8s' writes a ConfigMap or Secret with the bundle and an init container patch per node",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "EMIT"),
+	}
+	K8sImageFlag = &cli.StringFlag{
+		Name:    "k8s-image",
+		Usage:   "op-conductor-init image run by the init container",
+		Value:   "docker.io/golemnetwork/op-conductor-init:latest",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "K8S_IMAGE"),
+	}
+	K8sResourceFlag = &cli.StringFlag{
+		Name:    "k8s-resource",
+		Usage:   "Kind of resource holding each bundle: configmap or secret",
+		Value:   "configmap",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "K8S_RESOURCE"),
+	}
+	K8sStatefulSetFlag = &cli.StringFlag{
+		Name:    "k8s-statefulset",
+		Usage:   "Name of the StatefulSet running each node's op-conductor, {id} is the server ID and required with several nodes",
+		Value:   "{id}",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "K8S_STATEFULSET"),
+	}
+	K8sVolumeFlag = &cli.StringFlag{
+		Name:    "k8s-volume",
+		Usage:   "Name of the pod volume holding op-conductor's --raft.storage.dir",
+		Value:   "raft",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "K8S_VOLUME"),
+	}
+	SnapshotIndexFlag = &cli.Uint64Flag{
+		Name:    "snapshot-index",
+		Usage:   "Raft log index recorded in the generated snapshot (only with --mode snapshot)",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "SNAPSHOT_INDEX"),
+		Value:   1,
+	}
+	PreflightFlag = &cli.BoolFlag{
+		Name:    "preflight",
+		Usage:   "Resolve every node's host and dial its consensus port before generating state",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "PREFLIGHT"),
+	}
+	PreflightTimeoutFlag = &cli.DurationFlag{
+		Name:    "preflight-timeout",
+		Usage:   "Timeout for resolving and dialing each node during --preflight",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "PREFLIGHT_TIMEOUT"),
+		Value:   5 * time.Second,
+	}
 	// Flags for raft subcommands
 	StateDirFlag = &cli.StringFlag{
 		Name:     "state-dir",
@@ -58,6 +236,24 @@ var (
 		Required: true,
 		EnvVars:  opservice.PrefixEnvVar(EnvVarPrefix, "STATE_DIR"),
 	}
+	// VerifyStateDirFlag is an optional --state-dir: verify may use --cluster-dir instead
+	VerifyStateDirFlag = &cli.StringFlag{
+		Name:    "state-dir",
+		Usage:   "Directory containing raft state files of a single node",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "STATE_DIR"),
+	}
+	// SelfStateDirFlag is the --state-dir of generate, only used with --self. It has its own env var,
+	// so STATE_DIR set for install or info in the same environment does not leak into generate.
+	SelfStateDirFlag = &cli.StringFlag{
+		Name:    "state-dir",
+		Usage:   "State directory of the calling node that --self generates its state into (op-conductor's --raft.storage.dir)",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "SELF_STATE_DIR"),
+	}
+	ClusterDirFlag = &cli.StringFlag{
+		Name:    "cluster-dir",
+		Usage:   "Directory containing one raft state directory per node, verified together",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "CLUSTER_DIR"),
+	}
 	BackupDirFlag = &cli.StringFlag{
 		Name:     "backup-dir",
 		Usage:    "Directory for backup operations",
@@ -70,16 +266,121 @@ var (
 		Value:   false,
 		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "RESTORE_FORCE"),
 	}
+	// InstallBundleFlag is --bundle for install, where it names the bundle file instead of a format
+	InstallBundleFlag = &cli.StringFlag{
+		Name:     "bundle",
+		Usage:    "Path to a node bundle written by raft generate --bundle",
+		Required: true,
+		EnvVars:  opservice.PrefixEnvVar(EnvVarPrefix, "BUNDLE_FILE"),
+	}
+	InstallForceFlag = &cli.BoolFlag{
+		Name:    "force",
+		Usage:   "Replace existing state that differs from the bundle",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "INSTALL_FORCE"),
+	}
+	InstallIfEmptyFlag = &cli.BoolFlag{
+		Name:    "if-empty",
+		Usage:   "Only install when the state directory holds no Raft state, and succeed without changes otherwise",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "INSTALL_IF_EMPTY"),
+	}
+	AddServersFlag = &cli.StringFlag{
+		Name:    "add-servers",
+		Aliases: []string{"add"},
+		Usage:   "Comma-separated list of id=address pairs to add as voters (e.g., sequencer-4=sequencer-4:50050)",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ADD_SERVERS"),
+	}
+	RemoveServersFlag = &cli.StringFlag{
+		Name:    "remove-servers",
+		Usage:   "Comma-separated list of server IDs to remove",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "REMOVE_SERVERS"),
+	}
+	UpdateAddressesFlag = &cli.StringFlag{
+		Name:    "update-addresses",
+		Usage:   "Comma-separated list of id=address pairs giving existing servers a new address",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "UPDATE_ADDRESSES"),
+	}
+	SetSuffrageFlag = &cli.StringFlag{
+		Name:    "set-suffrage",
+		Usage:   "Comma-separated list of id=suffrage pairs (voter, nonvoter or staging)",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "SET_SUFFRAGE"),
+	}
+	AddressMapFlag = &cli.StringFlag{
+		Name:    "map",
+		Usage:   "Comma-separated list of old=new address pairs (e.g., sequencer-1.old:50050=sequencer-1.new:50050)",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "MAP"),
+	}
+	AddressRegexFlag = &cli.StringFlag{
+		Name:    "address-regex",
+		Usage:   "Regular expression matched against every server address, replaced with --address-replacement",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ADDRESS_REGEX"),
+	}
+	AddressReplacementFlag = &cli.StringFlag{
+		Name:    "address-replacement",
+		Usage:   "Replacement for --address-regex matches, may reference groups as $1 or ${name}",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "ADDRESS_REPLACEMENT"),
+	}
+	PeersFlag = &cli.StringFlag{
+		Name:     "peers",
+		Usage:    "Path to a peers.json file with the surviving cluster configuration (HashiCorp Raft format: id, address, non_voter)",
+		Required: true,
+		EnvVars:  opservice.PrefixEnvVar(EnvVarPrefix, "PEERS"),
+	}
+	DryRunFlag = &cli.BoolFlag{
+		Name:    "dry-run",
+		Usage:   "Show the membership change without writing it",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "DRY_RUN"),
+	}
+	FormatFlag = &cli.StringFlag{
+		Name:    "format",
+		Usage:   "Output format: text, json or yaml",
+		Value:   "text",
+		EnvVars: opservice.PrefixEnvVar(EnvVarPrefix, "FORMAT"),
+	}
 )
 
 var Flags = []cli.Flag{
+	SpecFlag,
 	NodesFlag,
 	ServerIDsFlag,
+	ReplicasFlag,
+	FromConductorEnvFlag,
+	FromLiveFlag,
+	IDTemplateFlag,
+	AddressTemplateFlag,
+	RPCTemplateFlag,
+	NamespaceFlag,
+	OrdinalOffsetFlag,
+	AddressOverridesFlag,
+	NodeRPCsFlag,
+	NonVotersFlag,
+	StagingFlag,
 	OutputDirFlag,
 	InitialLeaderFlag,
+	NoInitialLeaderFlag,
 	InitialTermFlag,
+	InitialIndexFlag,
+	AboveStateFlag,
+	BumpTermFromFlag,
 	NetworkFlag,
 	ForceFlag,
+	EnsureFlag,
+	SelfFlag,
+	SelfStateDirFlag,
+	UnsafePayloadFlag,
+	UnsafePayloadRPCFlag,
+	UnsafePayloadBlockFlag,
+	ModeFlag,
+	SnapshotIndexFlag,
+	BundleFlag,
+	ConductorStorageDirFlag,
+	ConductorFlagsJSONFlag,
+	EmitFlag,
+	K8sImageFlag,
+	K8sResourceFlag,
+	K8sStatefulSetFlag,
+	K8sVolumeFlag,
+	PreflightFlag,
+	PreflightTimeoutFlag,
 }
 
 func init() {
@@ -0,0 +1,54 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	opcflags "github.com/ethereum-optimism/optimism/op-conductor/flags"
+	"github.com/golem-base/op-conductor-init/pkg/config"
+)
+
+// Files with the op-conductor configuration written into every node directory
+const (
+	ConductorEnvFile   = "conductor.env"
+	ConductorFlagsFile = "conductor-flags.json"
+)
+
+// writeConductorFiles writes the op-conductor configuration of a node into its directory
+func (g *Generator) writeConductorFiles(nodeDir string, node config.NodeConfig) error {
+	conductorFlags, err := g.cfg.ConductorFlags(node)
+	if err != nil {
+		return err
+	}
+
+	var env strings.Builder
+	fmt.Fprintf(&env, "# op-conductor configuration for %s, generated by op-conductor-init\n", node.ServerID)
+	for _, flag := range conductorFlags {
+		fmt.Fprintln(&env, flag.Env())
+	}
+	if g.cfg.ConductorStorageDir == "" {
+		fmt.Fprintf(&env, "# Set %s to the directory this state is installed to\n", opcflags.RaftStorageDir.EnvVars[0])
+	}
+	if err := os.WriteFile(filepath.Join(nodeDir, ConductorEnvFile), []byte(env.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ConductorEnvFile, err)
+	}
+
+	if !g.cfg.ConductorFlagsJSON {
+		return nil
+	}
+	values := make(map[string]string, len(conductorFlags))
+	for _, flag := range conductorFlags {
+		values[flag.Name] = flag.Value
+	}
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", ConductorFlagsFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(nodeDir, ConductorFlagsFile), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ConductorFlagsFile, err)
+	}
+	return nil
+}
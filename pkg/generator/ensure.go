@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/raft"
+
+	"github.com/golem-base/op-conductor-init/pkg/config"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+// checkExisting compares the state in the output directory with the requested configuration for --ensure.
+// It returns false when no node has any state, true when the state of every node matches, and an
+// error describing every difference otherwise.
+// Only membership, term, leader vote and log position are compared: the unsafe payload may be
+// fetched from a live chain and legitimately differ between runs.
+func (g *Generator) checkExisting() (bool, error) {
+	found := 0
+	for _, node := range g.cfg.Nodes {
+		if hasState(filepath.Join(g.cfg.OutputDir, node.ServerID)) {
+			found++
+		}
+	}
+	if found == 0 {
+		return false, nil
+	}
+
+	leader := g.cfg.InitialLeader
+	if leader == config.AutoInitialLeader {
+		leader = g.existingLeader()
+	}
+
+	var result *multierror.Error
+	expected := g.createConfiguration()
+	for _, node := range g.cfg.Nodes {
+		nodeDir := filepath.Join(g.cfg.OutputDir, node.ServerID)
+		if !hasState(nodeDir) {
+			result = multierror.Append(result, fmt.Errorf("%s: no state found", node.ServerID))
+			continue
+		}
+		for _, problem := range g.compareNode(nodeDir, node, leader, expected) {
+			result = multierror.Append(result, fmt.Errorf("%s: %s", node.ServerID, problem))
+		}
+	}
+
+	if err := result.ErrorOrNil(); err != nil {
+		return true, fmt.Errorf("existing state in %s does not match the requested configuration: %w", g.cfg.OutputDir, err)
+	}
+	return true, nil
+}
+
+// compareNode lists the differences between a node's state and the state generate would write
+func (g *Generator) compareNode(nodeDir string, node config.NodeConfig, leader string, expected raft.Configuration) []string {
+	var problems []string
+
+	stable, err := store.ReadStableStore(filepath.Join(nodeDir, store.StableStoreFile))
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	if stable.CurrentTerm != g.cfg.InitialTerm {
+		problems = append(problems, fmt.Sprintf("current term is %d, expected %d", stable.CurrentTerm, g.cfg.InitialTerm))
+	}
+	switch {
+	case node.ServerID == leader && (stable.LastVoteCand != leader || stable.LastVoteTerm != g.cfg.InitialTerm):
+		problems = append(problems, fmt.Sprintf("expected a vote for itself in term %d, found %s", g.cfg.InitialTerm, describeVote(stable)))
+	case node.ServerID != leader && stable.HasVote:
+		problems = append(problems, fmt.Sprintf("expected no vote, found %s", describeVote(stable)))
+	}
+
+	logs, err := store.ReadLogStore(filepath.Join(nodeDir, store.LogStoreFile))
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	snapshots, err := store.ReadSnapshots(nodeDir)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+
+	lastIndex := g.cfg.InitialIndex
+	if g.cfg.UnsafePayloadFile != "" || g.cfg.UnsafePayloadRPC != "" {
+		lastIndex++
+	}
+	// A log starting above index 1 is anchored by a snapshot just below it
+	snapshotIndex := g.cfg.InitialIndex - 1
+	if g.cfg.Mode == config.ModeSnapshot {
+		lastIndex = 0
+		snapshotIndex = g.cfg.SnapshotIndex
+	}
+	switch {
+	case snapshotIndex == 0:
+		if len(snapshots) > 0 {
+			problems = append(problems, fmt.Sprintf("found %d snapshot(s), the cluster has compacted its log", len(snapshots)))
+		}
+	case len(snapshots) == 0:
+		problems = append(problems, "expected a snapshot, found none")
+	case snapshots[0].Index != snapshotIndex || snapshots[0].Term != g.cfg.InitialTerm:
+		problems = append(problems, fmt.Sprintf("latest snapshot is at index %d in term %d, expected index %d in term %d",
+			snapshots[0].Index, snapshots[0].Term, snapshotIndex, g.cfg.InitialTerm))
+	}
+	if logs.LastIndex != lastIndex {
+		problems = append(problems, fmt.Sprintf("log ends at index %d, expected %d", logs.LastIndex, lastIndex))
+	}
+
+	configuration, _, ok := store.LatestConfiguration(logs, snapshots)
+	if !ok {
+		return append(problems, "no membership configuration found")
+	}
+	for _, line := range diffServers(configuration.Servers, expected.Servers) {
+		problems = append(problems, "membership differs: "+line)
+	}
+
+	return problems
+}
+
+// existingLeader returns the node whose state records a vote for itself, for --initial-leader auto
+func (g *Generator) existingLeader() string {
+	for _, node := range g.cfg.Nodes {
+		stable, err := store.ReadStableStore(filepath.Join(g.cfg.OutputDir, node.ServerID, store.StableStoreFile))
+		if err == nil && stable.LastVoteCand == node.ServerID && node.Suffrage == raft.Voter {
+			return node.ServerID
+		}
+	}
+	return ""
+}
+
+// hasState reports whether a node directory holds any Raft state
+func hasState(nodeDir string) bool {
+	for _, name := range []string{store.StableStoreFile, store.LogStoreFile, store.SnapshotsDir} {
+		if _, err := os.Stat(filepath.Join(nodeDir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func describeVote(stable *store.StableState) string {
+	if !stable.HasVote {
+		return "no vote"
+	}
+	return fmt.Sprintf("a vote for %s in term %d", stable.LastVoteCand, stable.LastVoteTerm)
+}
+
+// diffServers describes how the found servers differ from the expected ones
+func diffServers(found, expected []raft.Server) []string {
+	var diff []string
+	existing := make(map[raft.ServerID]raft.Server, len(found))
+	for _, server := range found {
+		existing[server.ID] = server
+	}
+
+	for _, want := range expected {
+		got, ok := existing[want.ID]
+		delete(existing, want.ID)
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("%s (%s) is missing", want.ID, want.Address))
+		case got.Address != want.Address:
+			diff = append(diff, fmt.Sprintf("%s has address %s, expected %s", want.ID, got.Address, want.Address))
+		case got.Suffrage != want.Suffrage:
+			diff = append(diff, fmt.Sprintf("%s is a %s, expected %s", want.ID, got.Suffrage, want.Suffrage))
+		}
+	}
+	for _, server := range found {
+		if _, ok := existing[server.ID]; ok {
+			diff = append(diff, fmt.Sprintf("%s (%s) is not in the requested configuration", server.ID, server.Address))
+		}
+	}
+
+	return diff
+}
@@ -5,18 +5,45 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/hashicorp/raft"
 
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/golem-base/op-conductor-init/pkg/bundle"
 	"github.com/golem-base/op-conductor-init/pkg/config"
+	"github.com/golem-base/op-conductor-init/pkg/k8s"
+	"github.com/golem-base/op-conductor-init/pkg/leader"
+	"github.com/golem-base/op-conductor-init/pkg/payload"
+	"github.com/golem-base/op-conductor-init/pkg/preflight"
 	"github.com/golem-base/op-conductor-init/pkg/store"
 )
 
+// leaderSelectionTimeout bounds the sync status query of each op-node for --initial-leader auto
+const leaderSelectionTimeout = 10 * time.Second
+
+// clusterState is the Raft state shared by every node of the cluster
+type clusterState struct {
+	entries  []*raft.Log
+	snapshot *snapshotState
+}
+
+// snapshotState describes the snapshot written in snapshot mode
+type snapshotState struct {
+	index         uint64
+	term          uint64
+	configuration raft.Configuration
+	data          []byte
+}
+
 // Generator handles the generation of pre-configured Raft state
 type Generator struct {
 	cfg *config.Config
 	log log.Logger
+
+	// selection is set when the initial leader was selected automatically
+	selection *leader.Selection
 }
 
 // New creates a new Generator instance
@@ -31,17 +58,34 @@ func New(cfg *config.Config, log log.Logger) *Generator {
 func (g *Generator) Generate(ctx context.Context) error {
 	g.log.Info("Starting Raft state generation",
 		"nodes", len(g.cfg.Nodes),
-		"initial_leader", g.cfg.InitialLeader,
+		"initial_leader", g.cfg.LeaderName(),
 		"output_dir", g.cfg.OutputDir,
 	)
 
-	// Check for existing files if not forcing
+	if g.cfg.Self != "" {
+		return g.generateSelf(ctx)
+	}
+
+	// Checked first, so an unchanged cluster needs none of the nodes to be reachable
+	if g.cfg.Ensure {
+		existing, err := g.checkExisting()
+		if err != nil {
+			return err
+		}
+		if existing {
+			g.log.Info("Existing state already matches the requested configuration, nothing written", "output_dir", g.cfg.OutputDir)
+			return nil
+		}
+	}
+
+	// Check for existing files if not forcing, before any node or op-node is contacted
 	if !g.cfg.Force {
 		existingFiles := []string{}
 		for _, node := range g.cfg.Nodes {
 			nodeDir := filepath.Join(g.cfg.OutputDir, node.ServerID)
-			stableDbPath := filepath.Join(nodeDir, "raft-stable.db")
-			logDbPath := filepath.Join(nodeDir, "raft-log.db")
+			stableDbPath := filepath.Join(nodeDir, store.StableStoreFile)
+			logDbPath := filepath.Join(nodeDir, store.LogStoreFile)
+			snapshotsPath := filepath.Join(nodeDir, store.SnapshotsDir)
 
 			if _, err := os.Stat(stableDbPath); err == nil {
 				existingFiles = append(existingFiles, stableDbPath)
@@ -49,6 +93,9 @@ func (g *Generator) Generate(ctx context.Context) error {
 			if _, err := os.Stat(logDbPath); err == nil {
 				existingFiles = append(existingFiles, logDbPath)
 			}
+			if _, err := os.Stat(snapshotsPath); err == nil {
+				existingFiles = append(existingFiles, snapshotsPath)
+			}
 		}
 
 		if len(existingFiles) > 0 {
@@ -60,13 +107,30 @@ func (g *Generator) Generate(ctx context.Context) error {
 		}
 	}
 
-	// Create output directory
-	if err := os.MkdirAll(g.cfg.OutputDir, 0o755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	if g.cfg.Preflight {
+		g.log.Info("Running preflight checks", "timeout", g.cfg.PreflightTimeout)
+		if err := preflight.Check(ctx, g.cfg.Nodes, g.cfg.PreflightTimeout); err != nil {
+			return fmt.Errorf("preflight checks failed: %w", err)
+		}
 	}
 
-	// Create configuration entry using official raft types
-	configEntry := g.createConfigurationEntry()
+	if g.cfg.InitialLeader == config.AutoInitialLeader {
+		if err := g.selectLeader(ctx); err != nil {
+			return err
+		}
+	}
+
+	state, err := g.buildClusterState(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Write everything into a staging directory first, so a failure never leaves partial state
+	stagingDir, err := createStagingDir(g.cfg.OutputDir)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stagingDir)
 
 	// Generate state for each node
 	for _, node := range g.cfg.Nodes {
@@ -75,50 +139,193 @@ func (g *Generator) Generate(ctx context.Context) error {
 		g.log.Info("Generating state for node",
 			"server_id", node.ServerID,
 			"address", node.Address,
+			"suffrage", node.Suffrage,
+			"labels", config.SortedLabels(node.Labels),
 			"is_leader", isLeader,
 		)
 
-		if err := g.createNodeState(node, configEntry, isLeader); err != nil {
+		if err := g.createNodeState(stagingDir, node, state, isLeader); err != nil {
 			return fmt.Errorf("failed to create state for node %s: %w", node.ServerID, err)
 		}
 	}
 
+	now := time.Now()
+	if g.cfg.Bundle != "" {
+		for _, node := range g.cfg.Nodes {
+			if err := g.createBundle(stagingDir, node, now); err != nil {
+				return fmt.Errorf("failed to create bundle for node %s: %w", node.ServerID, err)
+			}
+		}
+	}
+
+	if g.cfg.Emit == config.EmitK8s {
+		if err := k8s.Write(filepath.Join(stagingDir, k8s.Dir), stagingDir, g.cfg); err != nil {
+			return fmt.Errorf("failed to write Kubernetes manifests: %w", err)
+		}
+	}
+
+	backupDir, err := publishOutput(stagingDir, g.cfg.OutputDir, g.cfg.Force, now)
+	if err != nil {
+		return err
+	}
+	if backupDir != "" {
+		g.log.Info("Kept previous output as backup", "path", backupDir)
+	}
+
 	g.log.Info("Successfully generated Raft state for all nodes")
-	g.printSummary()
+	g.printSummary(state)
 
 	return nil
 }
 
-// createConfigurationEntry creates the initial Raft configuration log entry
-func (g *Generator) createConfigurationEntry() *raft.Log {
+// selectLeader replaces --initial-leader auto with the voter whose op-node reports the highest unsafe head
+func (g *Generator) selectLeader(ctx context.Context) error {
+	g.log.Info("Selecting initial leader from op-node sync status", "timeout", leaderSelectionTimeout)
+	selection, err := leader.Select(ctx, g.cfg.Nodes, leaderSelectionTimeout)
+	if err != nil {
+		for _, head := range selection.Heads {
+			g.log.Error("  Failed to query op-node", "server_id", head.ServerID, "rpc", head.RPC, "err", head.Err)
+		}
+		return fmt.Errorf("failed to select initial leader: %w", err)
+	}
+
+	g.selection = selection
+	g.cfg.InitialLeader = selection.Leader
+	g.log.Info("Selected initial leader", "server_id", selection.Leader)
+	return nil
+}
+
+// buildClusterState creates the log entries or snapshot shared by all nodes
+func (g *Generator) buildClusterState(ctx context.Context) (*clusterState, error) {
+	// Seed the consensus FSM with the initial unsafe head, if requested
+	envelope, err := g.loadUnsafePayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	configuration := g.createConfiguration()
+
+	if g.cfg.Mode == config.ModeSnapshot {
+		if envelope == nil {
+			return nil, fmt.Errorf("snapshot mode requires an unsafe payload, op-conductor's FSM cannot restore an empty snapshot")
+		}
+		data, err := payload.Encode(envelope)
+		if err != nil {
+			return nil, err
+		}
+
+		return &clusterState{
+			snapshot: &snapshotState{
+				index:         g.cfg.SnapshotIndex,
+				term:          g.cfg.InitialTerm,
+				configuration: configuration,
+				data:          data,
+			},
+		}, nil
+	}
+
+	// Create configuration entry using official raft types
+	state := &clusterState{entries: []*raft.Log{g.createConfigurationEntry(configuration)}}
+	if envelope != nil {
+		payloadEntry, err := g.createPayloadEntry(envelope, g.cfg.InitialIndex+1)
+		if err != nil {
+			return nil, err
+		}
+		state.entries = append(state.entries, payloadEntry)
+	}
+
+	// Raft applies every entry after the last snapshot, so a log starting above index 1 needs a snapshot
+	// just below it. The config requires a payload in that case, op-conductor cannot restore an empty snapshot.
+	if g.cfg.InitialIndex > 1 {
+		data, err := payload.Encode(envelope)
+		if err != nil {
+			return nil, err
+		}
+		state.snapshot = &snapshotState{
+			index:         g.cfg.InitialIndex - 1,
+			term:          g.cfg.InitialTerm,
+			configuration: configuration,
+			data:          data,
+		}
+	}
+
+	return state, nil
+}
+
+// createConfiguration creates the Raft membership configuration for all nodes
+func (g *Generator) createConfiguration() raft.Configuration {
 	servers := make([]raft.Server, len(g.cfg.Nodes))
 
 	for i, node := range g.cfg.Nodes {
 		servers[i] = raft.Server{
-			Suffrage: raft.Voter,
+			Suffrage: node.Suffrage,
 			ID:       raft.ServerID(node.ServerID),
 			Address:  raft.ServerAddress(node.Address),
 		}
 	}
 
-	config := raft.Configuration{
+	return raft.Configuration{
 		Servers: servers,
 	}
+}
 
+// createConfigurationEntry creates the initial Raft configuration log entry
+func (g *Generator) createConfigurationEntry(configuration raft.Configuration) *raft.Log {
 	// Encode configuration using official raft encoding
-	data := raft.EncodeConfiguration(config)
+	data := raft.EncodeConfiguration(configuration)
 
 	return &raft.Log{
-		Index: 1,
+		Index: g.cfg.InitialIndex,
 		Term:  g.cfg.InitialTerm,
 		Type:  raft.LogConfiguration,
 		Data:  data,
 	}
 }
 
-// createNodeState creates the Raft state files for a single node
-func (g *Generator) createNodeState(node config.NodeConfig, configEntry *raft.Log, isLeader bool) error {
-	nodeDir := filepath.Join(g.cfg.OutputDir, node.ServerID)
+// loadUnsafePayload loads the initial unsafe head payload from a file or an execution client RPC endpoint
+func (g *Generator) loadUnsafePayload(ctx context.Context) (*eth.ExecutionPayloadEnvelope, error) {
+	var (
+		envelope *eth.ExecutionPayloadEnvelope
+		err      error
+	)
+
+	switch {
+	case g.cfg.UnsafePayloadFile != "":
+		envelope, err = payload.LoadFile(g.cfg.UnsafePayloadFile)
+	case g.cfg.UnsafePayloadRPC != "":
+		envelope, err = payload.Fetch(ctx, g.cfg.UnsafePayloadRPC, g.cfg.UnsafePayloadBlock)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unsafe payload: %w", err)
+	}
+
+	g.log.Info("Loaded initial unsafe head payload",
+		"number", uint64(envelope.ExecutionPayload.BlockNumber),
+		"hash", envelope.ExecutionPayload.BlockHash,
+	)
+	return envelope, nil
+}
+
+// createPayloadEntry creates the raft.LogCommand entry carrying the initial unsafe head
+func (g *Generator) createPayloadEntry(envelope *eth.ExecutionPayloadEnvelope, index uint64) (*raft.Log, error) {
+	data, err := payload.Encode(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &raft.Log{
+		Index: index,
+		Term:  g.cfg.InitialTerm,
+		Type:  raft.LogCommand,
+		Data:  data,
+	}, nil
+}
+
+// createNodeState creates the Raft state files for a single node below outputDir
+func (g *Generator) createNodeState(outputDir string, node config.NodeConfig, state *clusterState, isLeader bool) error {
+	nodeDir := filepath.Join(outputDir, node.ServerID)
 
 	// Create node directory
 	if err := os.MkdirAll(nodeDir, 0o755); err != nil {
@@ -131,16 +338,55 @@ func (g *Generator) createNodeState(node config.NodeConfig, configEntry *raft.Lo
 	}
 
 	// Create log store
-	if err := store.CreateLogStore(nodeDir, configEntry); err != nil {
+	if err := store.CreateLogStore(nodeDir, state.entries...); err != nil {
 		return fmt.Errorf("failed to create log store: %w", err)
 	}
 
-	return nil
+	// Create snapshot
+	if snap := state.snapshot; snap != nil {
+		if err := store.CreateSnapshot(nodeDir, snap.index, snap.term, snap.configuration, snap.index, snap.data); err != nil {
+			return fmt.Errorf("failed to create snapshot: %w", err)
+		}
+	}
+
+	return g.writeConductorFiles(nodeDir, node)
+}
+
+// createBundle writes the deployment bundle of a node's state below outputDir
+func (g *Generator) createBundle(outputDir string, node config.NodeConfig, generatedAt time.Time) error {
+	conductorFlags, err := g.cfg.ConductorFlags(node)
+	if err != nil {
+		return err
+	}
+
+	manifest := &bundle.Manifest{
+		GeneratedAt:    generatedAt.UTC(),
+		ServerID:       node.ServerID,
+		Address:        node.Address,
+		Suffrage:       node.Suffrage.String(),
+		Network:        g.cfg.Network,
+		InitialLeader:  g.cfg.LeaderName(),
+		InitialTerm:    g.cfg.InitialTerm,
+		Mode:           g.cfg.Mode,
+		ConductorFlags: conductorFlags,
+	}
+	path := filepath.Join(outputDir, bundle.FileName(node.ServerID, g.cfg.Bundle))
+	return bundle.Write(path, g.cfg.Bundle, filepath.Join(outputDir, node.ServerID), manifest)
 }
 
 // printSummary prints a summary of the generated state
-func (g *Generator) printSummary() {
+func (g *Generator) printSummary(state *clusterState) {
 	g.log.Info("Generation summary:")
+	if g.selection != nil {
+		g.log.Info("Initial leader selected automatically", "leader", g.selection.Leader)
+		for _, head := range g.selection.Heads {
+			if head.Err != nil {
+				g.log.Info("  Unsafe head", "server_id", head.ServerID, "err", head.Err)
+				continue
+			}
+			g.log.Info("  Unsafe head", "server_id", head.ServerID, "number", head.Number, "hash", head.Hash)
+		}
+	}
 	g.log.Info("Generated files:")
 
 	for _, node := range g.cfg.Nodes {
@@ -148,10 +394,30 @@ func (g *Generator) printSummary() {
 		g.log.Info("  Node directory", "path", nodeDir)
 		g.log.Info("    - raft-log.db")
 		g.log.Info("    - raft-stable.db")
+		if state.snapshot != nil {
+			g.log.Info("    - snapshots/")
+		}
+		g.log.Info("    - " + ConductorEnvFile)
+		if g.cfg.ConductorFlagsJSON {
+			g.log.Info("    - " + ConductorFlagsFile)
+		}
+		if g.cfg.Bundle != "" {
+			g.log.Info("  Node bundle", "path", filepath.Join(g.cfg.OutputDir, bundle.FileName(node.ServerID, g.cfg.Bundle)))
+		}
+	}
+
+	if g.cfg.Emit == config.EmitK8s {
+		g.log.Info("  Kubernetes manifests", "path", filepath.Join(g.cfg.OutputDir, k8s.Dir))
 	}
 
 	g.log.Info("\nNext steps:")
-	g.log.Info("1. Copy the generated state to your persistent volumes")
-	g.log.Info("2. Ensure all sequencers have --raft.bootstrap=false")
+	if g.cfg.Emit == config.EmitK8s {
+		g.log.Info("1. Apply the *-raft-state.yaml manifests and patch each StatefulSet with its *-init-patch.yaml")
+	} else if g.cfg.Bundle != "" {
+		g.log.Info("1. Install each bundle with: raft install --bundle <bundle> --state-dir <raft.storage.dir>")
+	} else {
+		g.log.Info("1. Copy the generated state to your persistent volumes")
+	}
+	g.log.Info("2. Configure each op-conductor from its " + ConductorEnvFile + ", which sets --raft.bootstrap=false")
 	g.log.Info("3. Start all sequencers simultaneously")
 }
@@ -0,0 +1,568 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	boltdb "github.com/hashicorp/raft-boltdb/v2"
+
+	"github.com/ethereum-optimism/optimism/op-conductor/consensus"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/golem-base/op-conductor-init/pkg/config"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	return &config.Config{
+		Nodes: []config.NodeConfig{
+			{ServerID: "sequencer-1", Address: "sequencer-1:50050", Suffrage: raft.Voter},
+			{ServerID: "sequencer-2", Address: "sequencer-2:50050", Suffrage: raft.Voter},
+			{ServerID: "sequencer-3", Address: "sequencer-3:50050", Suffrage: raft.Nonvoter},
+		},
+		OutputDir:     filepath.Join(t.TempDir(), "raft-state"),
+		InitialLeader: "sequencer-1",
+		InitialTerm:   1,
+		InitialIndex:  1,
+		Mode:          config.ModeLog,
+	}
+}
+
+func readLog(t *testing.T, nodeDir string, index uint64) *raft.Log {
+	t.Helper()
+	store, err := boltdb.NewBoltStore(filepath.Join(nodeDir, "raft-log.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	entry := &raft.Log{}
+	if err := store.GetLog(index, entry); err != nil {
+		t.Fatalf("Failed to read log entry %d: %v", index, err)
+	}
+	return entry
+}
+
+func TestGenerateSuffrage(t *testing.T) {
+	cfg := testConfig(t)
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+
+	for _, node := range cfg.Nodes {
+		entry := readLog(t, filepath.Join(cfg.OutputDir, node.ServerID), 1)
+		if entry.Type != raft.LogConfiguration {
+			t.Fatalf("Expected configuration entry, got %v", entry.Type)
+		}
+
+		configuration := raft.DecodeConfiguration(entry.Data)
+		if len(configuration.Servers) != len(cfg.Nodes) {
+			t.Fatalf("Expected %d servers, got %d", len(cfg.Nodes), len(configuration.Servers))
+		}
+		for i, server := range configuration.Servers {
+			if server.Suffrage != cfg.Nodes[i].Suffrage {
+				t.Fatalf("Expected %s to have suffrage %s, got %s", server.ID, cfg.Nodes[i].Suffrage, server.Suffrage)
+			}
+		}
+	}
+}
+
+func TestGenerateInitialLeader(t *testing.T) {
+	for _, leader := range []string{"sequencer-1", ""} {
+		cfg := testConfig(t)
+		cfg.InitialLeader = leader
+		if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+			t.Fatalf("Failed to generate state: %v", err)
+		}
+
+		for _, node := range cfg.Nodes {
+			stable, err := store.ReadStableStore(filepath.Join(cfg.OutputDir, node.ServerID, store.StableStoreFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stable.CurrentTerm != cfg.InitialTerm {
+				t.Fatalf("Expected %s to have term %d, got %d", node.ServerID, cfg.InitialTerm, stable.CurrentTerm)
+			}
+			if wantVote := node.ServerID == leader; stable.HasVote != wantVote || (wantVote && stable.LastVoteCand != leader) {
+				t.Fatalf("Leader %q: unexpected vote on %s: %+v", leader, node.ServerID, stable)
+			}
+		}
+	}
+}
+
+func TestGenerateRefusesExistingStateBeforePreflight(t *testing.T) {
+	cfg := testConfig(t)
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+
+	// None of the nodes is reachable, the existing state must be reported instead
+	cfg.Preflight = true
+	cfg.PreflightTimeout = 100 * time.Millisecond
+	err := New(cfg, log.New()).Generate(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "refusing to overwrite") {
+		t.Fatalf("Expected existing state to be refused before preflight, got: %v", err)
+	}
+}
+
+func TestGenerateForceKeepsBackup(t *testing.T) {
+	cfg := testConfig(t)
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+	if err := New(cfg, log.New()).Generate(context.Background()); err == nil {
+		t.Fatal("Expected generation over existing state to fail without --force")
+	}
+
+	cfg.Force = true
+	cfg.InitialTerm = 2
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to regenerate state: %v", err)
+	}
+
+	backups, err := filepath.Glob(cfg.OutputDir + ".backup-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup of the previous output, got %v", backups)
+	}
+	for dir, term := range map[string]uint64{backups[0]: 1, cfg.OutputDir: 2} {
+		stable, err := store.ReadStableStore(filepath.Join(dir, "sequencer-1", store.StableStoreFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stable.CurrentTerm != term {
+			t.Fatalf("Expected term %d in %s, got %d", term, dir, stable.CurrentTerm)
+		}
+	}
+
+	if staging, _ := filepath.Glob(filepath.Join(filepath.Dir(cfg.OutputDir), ".*.tmp-*")); len(staging) != 0 {
+		t.Fatalf("Expected staging directories to be removed, got %v", staging)
+	}
+}
+
+func TestGenerateOnlyReplacesGeneratedEntries(t *testing.T) {
+	outputDir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(outputDir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	unrelated := filepath.Join(outputDir, "notes.txt")
+	if err := os.WriteFile(unrelated, []byte("keep me"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The current directory is resolved, so the staging directory is created next to it
+	cfg := testConfig(t)
+	cfg.OutputDir = "."
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to generate state into the current directory: %v", err)
+	}
+	cfg.Force = true
+	cfg.InitialTerm = 2
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to regenerate state: %v", err)
+	}
+
+	if data, err := os.ReadFile(unrelated); err != nil || string(data) != "keep me" {
+		t.Fatalf("Expected unrelated files to stay in place, got %q, %v", data, err)
+	}
+	backups, _ := filepath.Glob(outputDir + ".backup-*")
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup of the previous output, got %v", backups)
+	}
+	entries, err := os.ReadDir(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(cfg.Nodes) {
+		t.Fatalf("Expected only the node directories to be backed up, got %d entries", len(entries))
+	}
+	stable, err := store.ReadStableStore(filepath.Join(outputDir, "sequencer-1", store.StableStoreFile))
+	if err != nil || stable.CurrentTerm != 2 {
+		t.Fatalf("Expected the new state in term 2, got %+v, %v", stable, err)
+	}
+	if staging, _ := filepath.Glob(filepath.Join(filepath.Dir(outputDir), ".*.tmp-*")); len(staging) != 0 {
+		t.Fatalf("Expected staging directories to be removed, got %v", staging)
+	}
+}
+
+func TestStagingDirOutsideOutputDir(t *testing.T) {
+	if _, err := createStagingDir(string(filepath.Separator)); err == nil {
+		t.Fatal("Expected an output directory without a parent to be rejected")
+	}
+
+	outputDir := t.TempDir()
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(outputDir, link); err != nil {
+		t.Fatal(err)
+	}
+	stagingDir, err := createStagingDir(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stagingDir)
+	if filepath.Dir(stagingDir) != filepath.Dir(outputDir) {
+		t.Fatalf("Expected the staging directory next to the resolved output directory, got %s", stagingDir)
+	}
+}
+
+func TestGenerateFailureKeepsOutput(t *testing.T) {
+	cfg := testConfig(t)
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+
+	// Bundles are written after all node directories, so generation fails after the nodes were written
+	cfg.Force = true
+	cfg.InitialTerm = 2
+	cfg.Bundle = "zip"
+	if err := New(cfg, log.New()).Generate(context.Background()); err == nil {
+		t.Fatal("Expected generation to fail")
+	}
+
+	for _, id := range []string{"sequencer-1", "sequencer-2", "sequencer-3"} {
+		stable, err := store.ReadStableStore(filepath.Join(cfg.OutputDir, id, store.StableStoreFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stable.CurrentTerm != 1 {
+			t.Fatalf("Expected %s to keep the previous state, got term %d", id, stable.CurrentTerm)
+		}
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(cfg.OutputDir)); len(entries) != 1 {
+		t.Fatalf("Expected only the output directory, got %d entries", len(entries))
+	}
+}
+
+func TestGenerateConductorFiles(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.ConductorStorageDir = "/data/raft"
+	cfg.ConductorFlagsJSON = true
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+
+	nodeDir := filepath.Join(cfg.OutputDir, "sequencer-2")
+	env, err := os.ReadFile(filepath.Join(nodeDir, ConductorEnvFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"OP_CONDUCTOR_RAFT_SERVER_ID=sequencer-2",
+		"OP_CONDUCTOR_RAFT_BOOTSTRAP=false",
+		"OP_CONDUCTOR_CONSENSUS_ADDR=0.0.0.0",
+		"OP_CONDUCTOR_CONSENSUS_PORT=50050",
+		"OP_CONDUCTOR_CONSENSUS_ADVERTISED=sequencer-2:50050",
+		"OP_CONDUCTOR_RAFT_STORAGE_DIR=/data/raft",
+	} {
+		if !strings.Contains(string(env), line+"\n") {
+			t.Fatalf("Expected %s in %s, got:\n%s", line, ConductorEnvFile, env)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(nodeDir, ConductorFlagsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]string{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		t.Fatal(err)
+	}
+	if values["raft.server.id"] != "sequencer-2" || values["raft.storage.dir"] != "/data/raft" {
+		t.Fatalf("Unexpected %s: %v", ConductorFlagsFile, values)
+	}
+}
+
+func TestGenerateEnsure(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Ensure = true
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+
+	// Matching state is left alone
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Expected matching state to be accepted: %v", err)
+	}
+	if backups, _ := filepath.Glob(cfg.OutputDir + ".backup-*"); len(backups) != 0 {
+		t.Fatalf("Expected matching state not to be regenerated, got backups %v", backups)
+	}
+
+	// A cluster that has moved on is refused
+	nodeDir := filepath.Join(cfg.OutputDir, "sequencer-2")
+	if err := store.AppendLog(nodeDir, &raft.Log{Index: 2, Term: 1, Type: raft.LogNoop}); err != nil {
+		t.Fatal(err)
+	}
+	changed := testConfig(t)
+	changed.OutputDir = cfg.OutputDir
+	changed.Ensure = true
+	changed.Nodes[2].Address = "sequencer-3.new:50050"
+
+	err := New(changed, log.New()).Generate(context.Background())
+	if err == nil {
+		t.Fatal("Expected diverged state to be refused")
+	}
+	for _, problem := range []string{
+		"sequencer-2: log ends at index 2, expected 1",
+		"sequencer-1: membership differs: sequencer-3 has address sequencer-3:50050, expected sequencer-3.new:50050",
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Fatalf("Expected %q in error, got: %v", problem, err)
+		}
+	}
+}
+
+func TestGenerateSelf(t *testing.T) {
+	cfg := testConfig(t)
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+	want := readLog(t, filepath.Join(cfg.OutputDir, "sequencer-1"), 1)
+
+	for _, node := range cfg.Nodes {
+		self := testConfig(t)
+		self.Self = node.ServerID
+		self.StateDir = filepath.Join(t.TempDir(), "raft")
+		if err := New(self, log.New()).Generate(context.Background()); err != nil {
+			t.Fatalf("Failed to generate state of %s: %v", node.ServerID, err)
+		}
+
+		// Every node must compute exactly the entry a central generation writes
+		got := readLog(t, self.StateDir, 1)
+		if got.Index != want.Index || got.Term != want.Term || got.Type != want.Type || !bytes.Equal(got.Data, want.Data) {
+			t.Fatalf("Configuration entry of %s differs from the central generation", node.ServerID)
+		}
+		stable, err := store.ReadStableStore(filepath.Join(self.StateDir, store.StableStoreFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stable.HasVote != (node.ServerID == cfg.InitialLeader) {
+			t.Fatalf("Unexpected vote on %s: %+v", node.ServerID, stable)
+		}
+		if _, err := os.Stat(filepath.Join(self.StateDir, node.ServerID)); !os.IsNotExist(err) {
+			t.Fatalf("Expected state directly in the state directory, found a %s subdirectory", node.ServerID)
+		}
+
+		// Existing state is left alone on the next start
+		if err := New(self, log.New()).Generate(context.Background()); err != nil {
+			t.Fatalf("Expected existing state of %s to be kept: %v", node.ServerID, err)
+		}
+	}
+
+	self := testConfig(t)
+	self.Self = "sequencer-1"
+	self.StateDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(self.StateDir, store.LogStoreFile), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(self, log.New()).Generate(context.Background()); err == nil || !strings.Contains(err.Error(), "incomplete") {
+		t.Fatalf("Expected incomplete state to be refused, got: %v", err)
+	}
+}
+
+func testEnvelope() *eth.ExecutionPayloadEnvelope {
+	beaconRoot := common.HexToHash("0xbeac0b")
+	return &eth.ExecutionPayloadEnvelope{
+		ParentBeaconBlockRoot: &beaconRoot,
+		ExecutionPayload: &eth.ExecutionPayload{
+			ParentHash:    common.HexToHash("0x01"),
+			BlockNumber:   1234,
+			BlockHash:     common.HexToHash("0x02"),
+			Timestamp:     1_700_000_000,
+			Withdrawals:   &types.Withdrawals{},
+			BlobGasUsed:   new(hexutil.Uint64),
+			ExcessBlobGas: new(hexutil.Uint64),
+		},
+	}
+}
+
+func TestGenerateUnsafePayload(t *testing.T) {
+	cfg := testConfig(t)
+
+	envelope := testEnvelope()
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.UnsafePayloadFile = filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(cfg.UnsafePayloadFile, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+
+	entry := readLog(t, filepath.Join(cfg.OutputDir, "sequencer-2"), 2)
+	if entry.Type != raft.LogCommand || entry.Term != cfg.InitialTerm {
+		t.Fatalf("Expected command entry in term %d, got %v in term %d", cfg.InitialTerm, entry.Type, entry.Term)
+	}
+
+	fsm := consensus.NewUnsafeHeadTracker(log.New())
+	if result := fsm.Apply(entry); result != nil {
+		t.Fatalf("FSM rejected payload entry: %v", result)
+	}
+	if fsm.UnsafeHead().ExecutionPayload.BlockHash != envelope.ExecutionPayload.BlockHash {
+		t.Fatalf("FSM recorded unexpected unsafe head %s", fsm.UnsafeHead().ExecutionPayload.BlockHash)
+	}
+}
+
+func TestGenerateSnapshotMode(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Mode = config.ModeSnapshot
+	cfg.SnapshotIndex = 1000
+	cfg.InitialTerm = 7
+
+	data, err := json.Marshal(testEnvelope())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.UnsafePayloadFile = filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(cfg.UnsafePayloadFile, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+
+	nodeDir := filepath.Join(cfg.OutputDir, "sequencer-1")
+	snapshots, err := raft.NewFileSnapshotStore(nodeDir, 1, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := snapshots.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("Expected 1 snapshot, got %d", len(list))
+	}
+
+	meta := list[0]
+	if meta.Index != 1000 || meta.Term != 7 || meta.ConfigurationIndex != 1000 {
+		t.Fatalf("Unexpected snapshot metadata: index=%d term=%d configuration_index=%d",
+			meta.Index, meta.Term, meta.ConfigurationIndex)
+	}
+	if len(meta.Configuration.Servers) != len(cfg.Nodes) {
+		t.Fatalf("Expected %d servers in snapshot, got %d", len(cfg.Nodes), len(meta.Configuration.Servers))
+	}
+
+	_, source, err := snapshots.Open(meta.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsm := consensus.NewUnsafeHeadTracker(log.New())
+	if err := fsm.Restore(source); err != nil {
+		t.Fatalf("FSM failed to restore snapshot: %v", err)
+	}
+	if fsm.UnsafeHead().ExecutionPayload.BlockNumber != 1234 {
+		t.Fatalf("Unexpected unsafe head %d", fsm.UnsafeHead().ExecutionPayload.BlockNumber)
+	}
+
+	logs, err := boltdb.NewBoltStore(filepath.Join(nodeDir, "raft-log.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logs.Close()
+	if last, err := logs.LastIndex(); err != nil || last != 0 {
+		t.Fatalf("Expected empty log store, got last index %d (err: %v)", last, err)
+	}
+}
+
+func TestGenerateAboveIndex(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Nodes = cfg.Nodes[:1]
+	cfg.InitialIndex = 100
+	cfg.InitialTerm = 9
+
+	data, err := json.Marshal(testEnvelope())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.UnsafePayloadFile = filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(cfg.UnsafePayloadFile, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Failed to generate state: %v", err)
+	}
+	nodeDir := filepath.Join(cfg.OutputDir, "sequencer-1")
+	if entry := readLog(t, nodeDir, 100); entry.Type != raft.LogConfiguration || entry.Term != 9 {
+		t.Fatalf("Expected configuration entry at index 100 in term 9, got %v in term %d", entry.Type, entry.Term)
+	}
+	if position, err := store.LastPosition(nodeDir); err != nil || *position != (store.Position{Index: 101, Term: 9}) {
+		t.Fatalf("Expected state to end at index 101 in term 9, got %+v (err: %v)", position, err)
+	}
+
+	cfg.Ensure = true
+	if err := New(cfg, log.New()).Generate(context.Background()); err != nil {
+		t.Fatalf("Expected --ensure to accept the generated state: %v", err)
+	}
+
+	// Start the node the way op-conductor does: committing must not reach for entries below index 100
+	logStore, err := boltdb.NewBoltStore(filepath.Join(nodeDir, store.LogStoreFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logStore.Close()
+	stableStore, err := boltdb.NewBoltStore(filepath.Join(nodeDir, store.StableStoreFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stableStore.Close()
+	snapshotStore, err := raft.NewFileSnapshotStore(nodeDir, store.SnapshotRetain, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, trans := raft.NewInmemTransport("sequencer-1:50050")
+
+	conf := raft.DefaultConfig()
+	conf.LocalID = "sequencer-1"
+	conf.Logger = hclog.NewNullLogger()
+	conf.HeartbeatTimeout = 50 * time.Millisecond
+	conf.ElectionTimeout = 50 * time.Millisecond
+	conf.LeaderLeaseTimeout = 50 * time.Millisecond
+	fsm := consensus.NewUnsafeHeadTracker(log.NewLogger(log.DiscardHandler()))
+
+	r, err := raft.NewRaft(conf, fsm, logStore, stableStore, snapshotStore, trans)
+	if err != nil {
+		t.Fatalf("Failed to start node: %v", err)
+	}
+	defer r.Shutdown()
+	select {
+	case <-r.LeaderCh():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Node did not become leader")
+	}
+	if err := r.Barrier(5 * time.Second).Error(); err != nil {
+		t.Fatalf("Node failed to commit its log: %v", err)
+	}
+	if index := r.AppliedIndex(); index <= 101 {
+		t.Fatalf("Expected entries after index 101 to be applied, applied up to %d", index)
+	}
+	if head := fsm.UnsafeHead(); head == nil || head.ExecutionPayload.BlockNumber != 1234 {
+		t.Fatalf("Expected unsafe head 1234, got %+v", head)
+	}
+}
@@ -0,0 +1,191 @@
+package generator
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// backupTimeFormat is the timestamp suffix of the backup of output replaced by a new generation
+const backupTimeFormat = "20060102-150405"
+
+// resolveOutputDir returns the absolute path of outputDir with symlinks resolved, so its parent is
+// the directory the staging directory is created in. An output directory that is its own parent,
+// the filesystem root, would contain its staging directory and is rejected.
+func resolveOutputDir(outputDir string) (string, error) {
+	dir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve output directory %s: %w", outputDir, err)
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to resolve output directory %s: %w", outputDir, err)
+	}
+	if filepath.Dir(dir) == dir {
+		return "", fmt.Errorf("output directory %s has no parent directory to stage the generated state in", outputDir)
+	}
+	return dir, nil
+}
+
+// createStagingDir creates the temporary sibling of outputDir that a generation is written to.
+// Being on the same filesystem as outputDir allows renaming its entries into place atomically.
+func createStagingDir(outputDir string) (string, error) {
+	outputDir, err := resolveOutputDir(outputDir)
+	if err != nil {
+		return "", err
+	}
+	parent, base := filepath.Split(outputDir)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create parent of output directory: %w", err)
+	}
+
+	dir, err := os.MkdirTemp(parent, "."+base+".tmp-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	if err := os.Chmod(dir, 0o755); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to set staging directory permissions: %w", err)
+	}
+	return dir, nil
+}
+
+// publishOutput moves every entry of the staging directory, the node directories, k8s/ and the
+// bundles, into outputDir. Nothing else in outputDir is touched. Existing entries of the same name
+// are only replaced with force: they are moved to a timestamped backup directory first and restored
+// if the new entries cannot all be moved into place, so outputDir never mixes old and new state.
+// Empty directories are replaced without a backup.
+// It returns the backup path, or an empty string when there was nothing to back up.
+func publishOutput(stagingDir, outputDir string, force bool, now time.Time) (string, error) {
+	outputDir, err := resolveOutputDir(outputDir)
+	if err != nil {
+		return "", err
+	}
+	if err := syncTree(stagingDir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(stagingDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read staging directory: %w", err)
+	}
+	var replaced, conflicts []string
+	for _, entry := range entries {
+		target := filepath.Join(outputDir, entry.Name())
+		if _, err := os.Lstat(target); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return "", fmt.Errorf("failed to inspect %s: %w", target, err)
+		}
+		if contents, err := os.ReadDir(target); err == nil && len(contents) == 0 {
+			if err := os.Remove(target); err != nil {
+				return "", fmt.Errorf("failed to remove empty directory %s: %w", target, err)
+			}
+			continue
+		}
+		if force {
+			replaced = append(replaced, entry.Name())
+		} else {
+			conflicts = append(conflicts, target)
+		}
+	}
+	if len(conflicts) > 0 {
+		return "", fmt.Errorf("refusing to replace %d existing entries without --force: %s", len(conflicts), strings.Join(conflicts, ", "))
+	}
+
+	backupDir := ""
+	if len(replaced) > 0 {
+		backupDir = fmt.Sprintf("%s.backup-%s", outputDir, now.UTC().Format(backupTimeFormat))
+		if err := os.Mkdir(backupDir, 0o755); err != nil {
+			return "", fmt.Errorf("failed to create backup directory: %w", err)
+		}
+	}
+
+	// restore undoes a partial publication: new entries are removed and backed up ones moved back
+	var backedUp, published []string
+	restore := func(cause error) error {
+		var problems []string
+		for _, name := range published {
+			if err := os.RemoveAll(filepath.Join(outputDir, name)); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+		for _, name := range backedUp {
+			if err := os.Rename(filepath.Join(backupDir, name), filepath.Join(outputDir, name)); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%w (restoring the previous output from %s failed: %s)", cause, backupDir, strings.Join(problems, "; "))
+		}
+		if backupDir != "" {
+			os.Remove(backupDir)
+		}
+		return cause
+	}
+
+	for _, name := range replaced {
+		if err := os.Rename(filepath.Join(outputDir, name), filepath.Join(backupDir, name)); err != nil {
+			return "", restore(fmt.Errorf("failed to back up %s: %w", name, err))
+		}
+		backedUp = append(backedUp, name)
+	}
+	for _, entry := range entries {
+		if err := os.Rename(filepath.Join(stagingDir, entry.Name()), filepath.Join(outputDir, entry.Name())); err != nil {
+			return "", restore(fmt.Errorf("failed to move generated %s into place: %w", entry.Name(), err))
+		}
+		published = append(published, entry.Name())
+	}
+
+	if err := syncDir(outputDir); err != nil {
+		return backupDir, err
+	}
+	if backupDir != "" {
+		if err := syncDir(backupDir); err != nil {
+			return backupDir, err
+		}
+	}
+	return backupDir, syncDir(filepath.Dir(outputDir))
+}
+
+// syncTree flushes every file and directory below root to stable storage
+func syncTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return syncDir(path)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s for sync: %w", path, err)
+		}
+		defer f.Close()
+		if err := f.Sync(); err != nil {
+			return fmt.Errorf("failed to sync %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// syncDir flushes a directory, persisting the creation and renaming of its entries
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open directory %s for sync: %w", path, err)
+	}
+	defer dir.Close()
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golem-base/op-conductor-init/pkg/config"
+	"github.com/golem-base/op-conductor-init/pkg/preflight"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+// generateSelf writes the state of the calling node only, directly into its state directory.
+// Every node derives its entries from the same configuration, so the nodes agree without exchanging files.
+func (g *Generator) generateSelf(ctx context.Context) error {
+	var node config.NodeConfig
+	for _, n := range g.cfg.Nodes {
+		if n.ServerID == g.cfg.Self {
+			node = n
+		}
+	}
+
+	// The stable store is moved into place last, so its presence marks complete state
+	if _, err := os.Stat(filepath.Join(g.cfg.StateDir, store.StableStoreFile)); err == nil {
+		g.log.Info("State directory already holds Raft state, nothing written", "server_id", node.ServerID, "state_dir", g.cfg.StateDir)
+		return nil
+	}
+	if hasState(g.cfg.StateDir) {
+		return fmt.Errorf("state directory %s holds incomplete Raft state without %s, remove it to generate again",
+			g.cfg.StateDir, store.StableStoreFile)
+	}
+
+	if g.cfg.Preflight {
+		g.log.Info("Running preflight checks", "timeout", g.cfg.PreflightTimeout)
+		if err := preflight.Check(ctx, g.cfg.Nodes, g.cfg.PreflightTimeout); err != nil {
+			return fmt.Errorf("preflight checks failed: %w", err)
+		}
+	}
+
+	state, err := g.buildClusterState(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The state directory is typically a volume mount point, so stage inside it and move the files up
+	if err := os.MkdirAll(g.cfg.StateDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	stagingDir, err := os.MkdirTemp(g.cfg.StateDir, ".generate-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	isLeader := node.ServerID == g.cfg.InitialLeader
+	g.log.Info("Generating state for this node",
+		"server_id", node.ServerID,
+		"address", node.Address,
+		"suffrage", node.Suffrage,
+		"is_leader", isLeader,
+		"state_dir", g.cfg.StateDir,
+	)
+	if err := g.createNodeState(stagingDir, node, state, isLeader); err != nil {
+		return fmt.Errorf("failed to create state for node %s: %w", node.ServerID, err)
+	}
+
+	nodeDir := filepath.Join(stagingDir, node.ServerID)
+	if err := syncTree(nodeDir); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(nodeDir)
+	if err != nil {
+		return fmt.Errorf("failed to read generated state: %w", err)
+	}
+	// Move the stable store last, see above
+	var names []string
+	for _, entry := range entries {
+		if entry.Name() != store.StableStoreFile {
+			names = append(names, entry.Name())
+		}
+	}
+	names = append(names, store.StableStoreFile)
+	for _, name := range names {
+		if err := os.Rename(filepath.Join(nodeDir, name), filepath.Join(g.cfg.StateDir, name)); err != nil {
+			return fmt.Errorf("failed to move %s into place: %w", name, err)
+		}
+	}
+	if err := syncDir(g.cfg.StateDir); err != nil {
+		return err
+	}
+
+	g.log.Info("Successfully generated Raft state for this node", "state_dir", g.cfg.StateDir, "files", strings.Join(names, ", "))
+	return nil
+}
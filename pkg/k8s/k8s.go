@@ -0,0 +1,203 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/golem-base/op-conductor-init/pkg/bundle"
+	"github.com/golem-base/op-conductor-init/pkg/config"
+)
+
+const (
+	// Dir is the directory below --output-dir the manifests are written to
+	Dir = "k8s"
+	// InitContainerName is the name of the init container installing the bundle
+	InitContainerName = "raft-state-install"
+	// bundleVolume is the pod volume the bundle resource is mounted from
+	bundleVolume    = "raft-state-bundle"
+	bundleMountPath = "/raft-bundle"
+
+	// maxObjectSize is the largest object the Kubernetes API server accepts
+	maxObjectSize = 1 << 20
+)
+
+type objectMeta struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type bundleResource struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   objectMeta        `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	BinaryData map[string]string `yaml:"binaryData,omitempty"`
+}
+
+type statefulSetPatch struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   objectMeta `yaml:"metadata"`
+	Spec       struct {
+		Template struct {
+			Spec podSpec `yaml:"spec"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+type podSpec struct {
+	InitContainers []container `yaml:"initContainers"`
+	Volumes        []volume    `yaml:"volumes"`
+}
+
+type container struct {
+	Name         string        `yaml:"name"`
+	Image        string        `yaml:"image"`
+	Args         []string      `yaml:"args"`
+	VolumeMounts []volumeMount `yaml:"volumeMounts"`
+}
+
+type volumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty"`
+}
+
+type volume struct {
+	Name      string           `yaml:"name"`
+	ConfigMap *configMapSource `yaml:"configMap,omitempty"`
+	Secret    *secretSource    `yaml:"secret,omitempty"`
+}
+
+type configMapSource struct {
+	Name string `yaml:"name"`
+}
+
+type secretSource struct {
+	SecretName string `yaml:"secretName"`
+}
+
+// ResourceName returns the name of the ConfigMap or Secret holding a node's bundle
+func ResourceName(serverID string) string {
+	return serverID + "-raft-state"
+}
+
+// Write writes the Kubernetes manifests of every node into dir.
+// The tar.gz bundles of the nodes are read from bundleDir.
+func Write(dir, bundleDir string, cfg *config.Config) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+
+	for _, node := range cfg.Nodes {
+		bundleName := bundle.FileName(node.ServerID, bundle.FormatTarGz)
+		data, err := os.ReadFile(filepath.Join(bundleDir, bundleName))
+		if err != nil {
+			return fmt.Errorf("failed to read bundle of %s: %w", node.ServerID, err)
+		}
+
+		resource := bundleManifest(cfg, node, bundleName, data)
+		if err := writeYAML(filepath.Join(dir, node.ServerID+"-raft-state.yaml"), resource); err != nil {
+			return err
+		}
+		if err := writeYAML(filepath.Join(dir, node.ServerID+"-init-patch.yaml"), initPatch(cfg, node, bundleName)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bundleManifest creates the ConfigMap or Secret holding a node's bundle
+func bundleManifest(cfg *config.Config, node config.NodeConfig, bundleName string, data []byte) *bundleResource {
+	encoded := map[string]string{bundleName: base64.StdEncoding.EncodeToString(data)}
+	resource := &bundleResource{
+		APIVersion: "v1",
+		Metadata: objectMeta{
+			Name:      ResourceName(node.ServerID),
+			Namespace: cfg.K8s.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "op-conductor",
+				"app.kubernetes.io/instance":   node.ServerID,
+				"app.kubernetes.io/component":  "raft-state",
+				"app.kubernetes.io/managed-by": "op-conductor-init",
+			},
+		},
+	}
+	if cfg.K8s.Resource == config.K8sSecret {
+		resource.Kind = "Secret"
+		resource.Type = "Opaque"
+		resource.Data = encoded
+	} else {
+		resource.Kind = "ConfigMap"
+		resource.BinaryData = encoded
+	}
+	return resource
+}
+
+// initPatch creates the strategic merge patch adding the install init container to a node's StatefulSet
+func initPatch(cfg *config.Config, node config.NodeConfig, bundleName string) *statefulSetPatch {
+	patch := &statefulSetPatch{
+		APIVersion: "apps/v1",
+		Kind:       "StatefulSet",
+		Metadata: objectMeta{
+			Name:      strings.ReplaceAll(cfg.K8s.StatefulSet, "{id}", node.ServerID),
+			Namespace: cfg.K8s.Namespace,
+		},
+	}
+
+	source := volume{Name: bundleVolume}
+	if cfg.K8s.Resource == config.K8sSecret {
+		source.Secret = &secretSource{SecretName: ResourceName(node.ServerID)}
+	} else {
+		source.ConfigMap = &configMapSource{Name: ResourceName(node.ServerID)}
+	}
+
+	patch.Spec.Template.Spec = podSpec{
+		InitContainers: []container{{
+			Name:  InitContainerName,
+			Image: cfg.K8s.Image,
+			// --if-empty makes the container a no-op once op-conductor has written to the volume
+			Args: []string{
+				"raft", "install",
+				"--bundle", bundleMountPath + "/" + bundleName,
+				"--state-dir", cfg.ConductorStorageDir,
+				"--if-empty",
+			},
+			VolumeMounts: []volumeMount{
+				{Name: cfg.K8s.Volume, MountPath: cfg.ConductorStorageDir},
+				{Name: bundleVolume, MountPath: bundleMountPath, ReadOnly: true},
+			},
+		}},
+		Volumes: []volume{source},
+	}
+	return patch
+}
+
+func writeYAML(path string, v interface{}) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if buf.Len() > maxObjectSize {
+		return fmt.Errorf("%s is %d bytes, more than the %d bytes Kubernetes accepts per object", filepath.Base(path), buf.Len(), maxObjectSize)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
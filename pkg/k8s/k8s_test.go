@@ -0,0 +1,124 @@
+package k8s
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"gopkg.in/yaml.v3"
+
+	"github.com/golem-base/op-conductor-init/pkg/bundle"
+	"github.com/golem-base/op-conductor-init/pkg/config"
+	"github.com/golem-base/op-conductor-init/pkg/store"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Nodes:               []config.NodeConfig{{ServerID: "sequencer-0", Address: "sequencer-0:50050", Suffrage: raft.Voter}},
+		ConductorStorageDir: "/data/raft",
+		K8s: config.K8sConfig{
+			Namespace:   "op",
+			Image:       "op-conductor-init:test",
+			Resource:    config.K8sConfigMap,
+			StatefulSet: "{id}-conductor",
+			Volume:      "raft",
+		},
+	}
+}
+
+func writeBundle(t *testing.T, outputDir string) {
+	t.Helper()
+	nodeDir := filepath.Join(outputDir, "sequencer-0")
+	if err := os.Mkdir(nodeDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateStableStore(nodeDir, "sequencer-0", 1, true); err != nil {
+		t.Fatal(err)
+	}
+	manifest := &bundle.Manifest{GeneratedAt: time.Now(), ServerID: "sequencer-0", Address: "sequencer-0:50050"}
+	if err := bundle.Write(filepath.Join(outputDir, "sequencer-0.tar.gz"), bundle.FormatTarGz, nodeDir, manifest); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readYAML(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	outputDir := t.TempDir()
+	writeBundle(t, outputDir)
+
+	cfg := testConfig()
+	if err := Write(filepath.Join(outputDir, Dir), outputDir, cfg); err != nil {
+		t.Fatalf("Failed to write manifests: %v", err)
+	}
+
+	resource := &bundleResource{}
+	readYAML(t, filepath.Join(outputDir, Dir, "sequencer-0-raft-state.yaml"), resource)
+	if resource.Kind != "ConfigMap" || resource.Metadata.Name != "sequencer-0-raft-state" || resource.Metadata.Namespace != "op" {
+		t.Fatalf("Unexpected bundle resource: %+v", resource.Metadata)
+	}
+
+	// The bundle in the ConfigMap must install like the original
+	data, err := base64.StdEncoding.DecodeString(resource.BinaryData["sequencer-0.tar.gz"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundlePath := filepath.Join(t.TempDir(), "sequencer-0.tar.gz")
+	if err := os.WriteFile(bundlePath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bundle.Install(bundlePath, t.TempDir(), false); err != nil {
+		t.Fatalf("Failed to install bundle from ConfigMap: %v", err)
+	}
+
+	patch := &statefulSetPatch{}
+	readYAML(t, filepath.Join(outputDir, Dir, "sequencer-0-init-patch.yaml"), patch)
+	if patch.Metadata.Name != "sequencer-0-conductor" {
+		t.Fatalf("Expected patch of sequencer-0-conductor, got %s", patch.Metadata.Name)
+	}
+	spec := patch.Spec.Template.Spec
+	if len(spec.InitContainers) != 1 || spec.InitContainers[0].Args[len(spec.InitContainers[0].Args)-1] != "--if-empty" {
+		t.Fatalf("Unexpected init containers: %+v", spec.InitContainers)
+	}
+	if mounts := spec.InitContainers[0].VolumeMounts; mounts[0].Name != "raft" || mounts[0].MountPath != "/data/raft" {
+		t.Fatalf("Expected state volume raft at /data/raft, got %+v", mounts[0])
+	}
+	if len(spec.Volumes) != 1 || spec.Volumes[0].ConfigMap == nil || spec.Volumes[0].ConfigMap.Name != "sequencer-0-raft-state" {
+		t.Fatalf("Unexpected volumes: %+v", spec.Volumes)
+	}
+}
+
+func TestWriteSecret(t *testing.T) {
+	outputDir := t.TempDir()
+	writeBundle(t, outputDir)
+
+	cfg := testConfig()
+	cfg.K8s.Resource = config.K8sSecret
+	if err := Write(filepath.Join(outputDir, Dir), outputDir, cfg); err != nil {
+		t.Fatalf("Failed to write manifests: %v", err)
+	}
+
+	resource := &bundleResource{}
+	readYAML(t, filepath.Join(outputDir, Dir, "sequencer-0-raft-state.yaml"), resource)
+	if resource.Kind != "Secret" || resource.Data["sequencer-0.tar.gz"] == "" || resource.BinaryData != nil {
+		t.Fatalf("Expected Secret holding the bundle, got %s", resource.Kind)
+	}
+
+	patch := &statefulSetPatch{}
+	readYAML(t, filepath.Join(outputDir, Dir, "sequencer-0-init-patch.yaml"), patch)
+	if volumes := patch.Spec.Template.Spec.Volumes; volumes[0].Secret == nil || volumes[0].Secret.SecretName != "sequencer-0-raft-state" {
+		t.Fatalf("Expected the Secret to be mounted, got %+v", volumes)
+	}
+}
@@ -0,0 +1,105 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/raft"
+
+	"github.com/ethereum-optimism/optimism/op-service/client"
+	"github.com/ethereum-optimism/optimism/op-service/sources"
+	"github.com/golem-base/op-conductor-init/pkg/config"
+)
+
+// Head is the unsafe head a node's op-node reported.
+// Err is set when the node could not be queried.
+type Head struct {
+	ServerID string
+	RPC      string
+	Number   uint64
+	Hash     common.Hash
+	Err      error
+}
+
+// Selection is the outcome of Select: the chosen leader and the heads it was chosen from
+type Selection struct {
+	Leader string
+	Heads  []Head
+}
+
+// Select queries optimism_syncStatus on the op-node of every voter and picks the voter with the
+// highest unsafe head. Ties are broken by node order, so the same heads always select the same
+// leader. Every voter must respond: a voter that cannot be queried may be the most advanced one,
+// so selection fails and the error lists every voter that could not be queried.
+func Select(ctx context.Context, nodes []config.NodeConfig, timeout time.Duration) (*Selection, error) {
+	var voters []config.NodeConfig
+	for _, node := range nodes {
+		if node.Suffrage == raft.Voter {
+			voters = append(voters, node)
+		}
+	}
+
+	selection := &Selection{Heads: make([]Head, len(voters))}
+	var wg sync.WaitGroup
+	for i, node := range voters {
+		wg.Add(1)
+		go func(i int, node config.NodeConfig) {
+			defer wg.Done()
+			selection.Heads[i] = queryHead(ctx, node, timeout)
+		}(i, node)
+	}
+	wg.Wait()
+
+	var result *multierror.Error
+	var best *Head
+	for i := range selection.Heads {
+		head := &selection.Heads[i]
+		if head.Err != nil {
+			result = multierror.Append(result, fmt.Errorf("%s: %w", head.ServerID, head.Err))
+		} else if best == nil || head.Number > best.Number {
+			best = head
+		}
+	}
+	if result != nil {
+		return selection, fmt.Errorf("every voter must report its sync status, a voter that is not queried may hold the highest unsafe head: %w", result)
+	}
+	if best == nil {
+		return selection, fmt.Errorf("no voters to select a leader from")
+	}
+
+	selection.Leader = best.ServerID
+	return selection, nil
+}
+
+func queryHead(ctx context.Context, node config.NodeConfig, timeout time.Duration) Head {
+	head := Head{ServerID: node.ServerID, RPC: node.RPC}
+	if node.RPC == "" {
+		head.Err = fmt.Errorf("no op-node RPC configured")
+		return head
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	rpcClient, err := rpc.DialContext(ctx, node.RPC)
+	if err != nil {
+		head.Err = fmt.Errorf("failed to dial %s: %w", node.RPC, err)
+		return head
+	}
+	defer rpcClient.Close()
+
+	status, err := sources.NewRollupClient(client.NewBaseRPCClient(rpcClient)).SyncStatus(ctx)
+	if err != nil {
+		head.Err = fmt.Errorf("failed to fetch sync status from %s: %w", node.RPC, err)
+		return head
+	}
+
+	head.Number = status.UnsafeL2.Number
+	head.Hash = status.UnsafeL2.Hash
+	return head
+}
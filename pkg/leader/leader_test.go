@@ -0,0 +1,81 @@
+package leader
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/hashicorp/raft"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/golem-base/op-conductor-init/pkg/config"
+)
+
+// stubOptimismAPI serves optimism_syncStatus with a fixed unsafe head
+type stubOptimismAPI struct {
+	unsafe uint64
+}
+
+func (api *stubOptimismAPI) SyncStatus(ctx context.Context) (*eth.SyncStatus, error) {
+	return &eth.SyncStatus{
+		UnsafeL2: eth.L2BlockRef{Number: api.unsafe, Hash: common.BigToHash(new(big.Int).SetUint64(api.unsafe))},
+	}, nil
+}
+
+func newStubOpNode(t *testing.T, unsafe uint64) string {
+	t.Helper()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("optimism", &stubOptimismAPI{unsafe: unsafe}); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Stop()
+	})
+	return httpServer.URL
+}
+
+func TestSelect(t *testing.T) {
+	// An endpoint nobody listens on
+	unreachable := httptest.NewServer(nil)
+	unreachable.Close()
+
+	nodes := []config.NodeConfig{
+		{ServerID: "sequencer-1", Suffrage: raft.Voter, RPC: newStubOpNode(t, 100)},
+		{ServerID: "sequencer-2", Suffrage: raft.Voter, RPC: newStubOpNode(t, 120)},
+		{ServerID: "sequencer-3", Suffrage: raft.Voter, RPC: newStubOpNode(t, 120)},
+		{ServerID: "sequencer-4", Suffrage: raft.Nonvoter, RPC: newStubOpNode(t, 200)},
+	}
+
+	selection, err := Select(context.Background(), nodes, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to select leader: %v", err)
+	}
+	// sequencer-2 and sequencer-3 tie, the first listed wins; the non-voter is never considered
+	if selection.Leader != "sequencer-2" {
+		t.Fatalf("Expected sequencer-2 to be selected, got %s", selection.Leader)
+	}
+	if len(selection.Heads) != 3 {
+		t.Fatalf("Expected heads of 3 voters, got %d", len(selection.Heads))
+	}
+	if head := selection.Heads[0]; head.Err != nil || head.Number != 100 {
+		t.Fatalf("Unexpected head for sequencer-1: %+v", head)
+	}
+
+	// The unreachable voter might be the most advanced one, so a partial view selects nothing
+	nodes = append(nodes, config.NodeConfig{ServerID: "sequencer-5", Suffrage: raft.Voter, RPC: unreachable.URL})
+	selection, err = Select(context.Background(), nodes, 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "sequencer-5") {
+		t.Fatalf("Expected selection to fail naming unreachable sequencer-5, got: %v", err)
+	}
+	if selection.Leader != "" || selection.Heads[3].Err == nil {
+		t.Fatalf("Expected no leader and an error for sequencer-5, got %+v", selection)
+	}
+}
@@ -0,0 +1,59 @@
+package live
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/ethereum-optimism/optimism/op-conductor/consensus"
+	conductorrpc "github.com/ethereum-optimism/optimism/op-conductor/rpc"
+)
+
+// Membership is the membership of a running cluster as reported by one of its conductors.
+// Leader is empty when the cluster has no leader, LeaderErr is set when it could not be queried.
+type Membership struct {
+	Endpoint  string
+	Servers   []consensus.ServerInfo
+	Version   uint64
+	Leader    string
+	LeaderErr error
+}
+
+// Capture queries conductor_clusterMembership and conductor_leaderWithID on a conductor.
+// The membership is required, while a missing leader is only recorded, since a cluster that
+// lost its quorum still reports its membership but cannot elect a leader.
+func Capture(ctx context.Context, endpoint string, timeout time.Duration) (*Membership, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	c, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial conductor %s: %w", endpoint, err)
+	}
+	api := conductorrpc.NewAPIClient(c)
+	defer api.Close()
+
+	membership, err := api.ClusterMembership(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cluster membership from %s: %w", endpoint, err)
+	}
+	if len(membership.Servers) == 0 {
+		return nil, fmt.Errorf("conductor %s reported an empty cluster membership", endpoint)
+	}
+
+	result := &Membership{
+		Endpoint: endpoint,
+		Servers:  membership.Servers,
+		Version:  membership.Version,
+	}
+	leader, err := api.LeaderWithID(ctx)
+	switch {
+	case err != nil:
+		result.LeaderErr = err
+	case leader != nil:
+		result.Leader = leader.ID
+	}
+	return result, nil
+}
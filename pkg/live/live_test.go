@@ -0,0 +1,86 @@
+package live
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/ethereum-optimism/optimism/op-conductor/consensus"
+)
+
+// stubConductorAPI serves the conductor membership and leader methods
+type stubConductorAPI struct {
+	membership consensus.ClusterMembership
+	leader     consensus.ServerInfo
+	leaderErr  error
+}
+
+func (api *stubConductorAPI) ClusterMembership(ctx context.Context) (*consensus.ClusterMembership, error) {
+	return &api.membership, nil
+}
+
+func (api *stubConductorAPI) LeaderWithID(ctx context.Context) (*consensus.ServerInfo, error) {
+	return &api.leader, api.leaderErr
+}
+
+func newStubConductor(t *testing.T, api *stubConductorAPI) string {
+	t.Helper()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("conductor", api); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Stop()
+	})
+	return httpServer.URL
+}
+
+func TestCapture(t *testing.T) {
+	servers := []consensus.ServerInfo{
+		{ID: "sequencer-1", Addr: "sequencer-1:50050", Suffrage: consensus.Voter},
+		{ID: "sequencer-2", Addr: "sequencer-2:50050", Suffrage: consensus.Voter},
+		{ID: "sequencer-3", Addr: "sequencer-3:50050", Suffrage: consensus.Nonvoter},
+	}
+	endpoint := newStubConductor(t, &stubConductorAPI{
+		membership: consensus.ClusterMembership{Servers: servers, Version: 7},
+		leader:     servers[1],
+	})
+
+	membership, err := Capture(context.Background(), endpoint, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to capture membership: %v", err)
+	}
+	if len(membership.Servers) != 3 || membership.Servers[2] != servers[2] || membership.Version != 7 {
+		t.Fatalf("Unexpected membership: %+v", membership)
+	}
+	if membership.Leader != "sequencer-2" || membership.LeaderErr != nil {
+		t.Fatalf("Expected leader sequencer-2, got %q (%v)", membership.Leader, membership.LeaderErr)
+	}
+}
+
+func TestCaptureWithoutLeader(t *testing.T) {
+	endpoint := newStubConductor(t, &stubConductorAPI{
+		membership: consensus.ClusterMembership{Servers: []consensus.ServerInfo{{ID: "sequencer-1", Addr: "sequencer-1:50050"}}},
+		leaderErr:  errors.New("no leader"),
+	})
+
+	membership, err := Capture(context.Background(), endpoint, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Expected the membership without a leader, got: %v", err)
+	}
+	if membership.Leader != "" || membership.LeaderErr == nil {
+		t.Fatalf("Expected a leader error, got leader %q", membership.Leader)
+	}
+
+	empty := newStubConductor(t, &stubConductorAPI{})
+	if _, err := Capture(context.Background(), empty, 5*time.Second); err == nil {
+		t.Fatal("Expected an empty membership to be rejected")
+	}
+}
@@ -0,0 +1,130 @@
+package payload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/sources"
+)
+
+// Encode serializes an execution payload envelope exactly as op-conductor's
+// consensus FSM expects it in a raft.LogCommand entry
+func Encode(envelope *eth.ExecutionPayloadEnvelope) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := envelope.MarshalSSZ(&buf); err != nil {
+		return nil, fmt.Errorf("failed to marshal payload envelope: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode deserializes a raft.LogCommand entry the same way op-conductor's consensus FSM does.
+// There is no way to know the block version, so the most recent version is tried first.
+func Decode(data []byte) (*eth.ExecutionPayloadEnvelope, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("payload data is empty")
+	}
+
+	envelope := &eth.ExecutionPayloadEnvelope{}
+	if err := envelope.UnmarshalSSZ(eth.BlockV4, uint32(len(data)), bytes.NewReader(data)); err != nil {
+		envelope = &eth.ExecutionPayloadEnvelope{}
+		if err := envelope.UnmarshalSSZ(eth.BlockV3, uint32(len(data)), bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal payload envelope: %w", err)
+		}
+	}
+	return envelope, nil
+}
+
+// LoadFile reads a JSON encoded execution payload envelope from a file
+func LoadFile(path string) (*eth.ExecutionPayloadEnvelope, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload file: %w", err)
+	}
+
+	envelope := &eth.ExecutionPayloadEnvelope{}
+	if err := json.Unmarshal(data, envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse payload file: %w", err)
+	}
+	if envelope.ExecutionPayload == nil {
+		return nil, fmt.Errorf("payload file %s does not contain an executionPayload", path)
+	}
+	return envelope, nil
+}
+
+// Fetch retrieves the execution payload envelope of a block from an execution client (op-geth) RPC endpoint.
+// The block is either a label (latest, safe, finalized) or a block number.
+// The block hash and transaction root are verified against the returned contents.
+// op-node does not serve execution payloads, an op-node endpoint is detected and rejected with a clear error.
+func Fetch(ctx context.Context, endpoint string, block string) (*eth.ExecutionPayloadEnvelope, error) {
+	client, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", endpoint, err)
+	}
+	defer client.Close()
+
+	blockArg, err := blockArgument(block)
+	if err != nil {
+		return nil, err
+	}
+
+	var rpcBlock *sources.RPCBlock
+	if err := client.CallContext(ctx, &rpcBlock, "eth_getBlockByNumber", blockArg, true); err != nil {
+		if status := opNodeStatus(ctx, client, err); status != nil {
+			return nil, fmt.Errorf("%s is an op-node rollup RPC endpoint (unsafe head %s), which does not serve execution payloads: "+
+				"use the op-geth RPC endpoint of the same sequencer", endpoint, status.UnsafeL2.ID())
+		}
+		return nil, fmt.Errorf("failed to fetch block %s: %w", block, err)
+	}
+	if rpcBlock == nil {
+		return nil, fmt.Errorf("block %s not found", block)
+	}
+
+	envelope, err := rpcBlock.ExecutionPayloadEnvelope(false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block %s: %w", block, err)
+	}
+	return envelope, nil
+}
+
+// opNodeStatus returns the sync status of the endpoint when eth_getBlockByNumber failed because
+// the endpoint is an op-node, or nil otherwise
+func opNodeStatus(ctx context.Context, client *rpc.Client, callErr error) *eth.SyncStatus {
+	var rpcErr rpc.Error
+	if !errors.As(callErr, &rpcErr) || rpcErr.ErrorCode() != methodNotFound {
+		return nil
+	}
+	var status *eth.SyncStatus
+	if err := client.CallContext(ctx, &status, "optimism_syncStatus"); err != nil {
+		return nil
+	}
+	return status
+}
+
+// methodNotFound is the JSON-RPC error code of an unknown method
+const methodNotFound = -32601
+
+// blockArgument converts a block label or number into an eth_getBlockByNumber argument
+func blockArgument(block string) (string, error) {
+	switch block = strings.TrimSpace(block); block {
+	case "", string(eth.Unsafe):
+		return string(eth.Unsafe), nil
+	case string(eth.Safe), string(eth.Finalized):
+		return block, nil
+	}
+
+	number, err := strconv.ParseUint(block, 0, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid block %q: expected latest, safe, finalized or a block number", block)
+	}
+	return hexutil.EncodeUint64(number), nil
+}
@@ -0,0 +1,142 @@
+package payload
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/hashicorp/raft"
+
+	"github.com/ethereum-optimism/optimism/op-conductor/consensus"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+)
+
+// stubEthAPI serves a single block over eth_getBlockByNumber
+type stubEthAPI struct {
+	block json.RawMessage
+}
+
+func (api *stubEthAPI) GetBlockByNumber(_ string, _ bool) (json.RawMessage, error) {
+	return api.block, nil
+}
+
+func testHeader() *types.Header {
+	zero := uint64(0)
+	beaconRoot := common.HexToHash("0xbeac0b")
+	return &types.Header{
+		ParentHash:       common.HexToHash("0x01"),
+		UncleHash:        types.EmptyUncleHash,
+		Root:             common.HexToHash("0x02"),
+		TxHash:           types.EmptyTxsHash,
+		ReceiptHash:      types.EmptyReceiptsHash,
+		Difficulty:       big.NewInt(0),
+		Number:           big.NewInt(1234),
+		GasLimit:         30_000_000,
+		Time:             1_700_000_000,
+		BaseFee:          big.NewInt(1_000_000_000),
+		WithdrawalsHash:  &types.EmptyWithdrawalsHash,
+		BlobGasUsed:      &zero,
+		ExcessBlobGas:    &zero,
+		ParentBeaconRoot: &beaconRoot,
+	}
+}
+
+func newStubRPC(t *testing.T, header *types.Header) string {
+	t.Helper()
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := map[string]interface{}{}
+	if err := json.Unmarshal(headerJSON, &block); err != nil {
+		t.Fatal(err)
+	}
+	block["transactions"] = []interface{}{}
+	block["withdrawals"] = []interface{}{}
+	blockJSON, err := json.Marshal(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &stubEthAPI{block: blockJSON}); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Stop()
+	})
+	return httpServer.URL
+}
+
+func TestFetchEncodesForConsensusFSM(t *testing.T) {
+	header := testHeader()
+	endpoint := newStubRPC(t, header)
+
+	envelope, err := Fetch(context.Background(), endpoint, "latest")
+	if err != nil {
+		t.Fatalf("Failed to fetch payload: %v", err)
+	}
+	if envelope.ExecutionPayload.BlockHash != header.Hash() {
+		t.Fatalf("Expected block hash %s, got %s", header.Hash(), envelope.ExecutionPayload.BlockHash)
+	}
+
+	data, err := Encode(envelope)
+	if err != nil {
+		t.Fatalf("Failed to encode payload: %v", err)
+	}
+
+	// The entry must be accepted by op-conductor's own FSM
+	fsm := consensus.NewUnsafeHeadTracker(log.New())
+	if result := fsm.Apply(&raft.Log{Index: 2, Term: 1, Type: raft.LogCommand, Data: data}); result != nil {
+		t.Fatalf("FSM rejected payload: %v", result)
+	}
+	if fsm.UnsafeHead().ExecutionPayload.BlockHash != header.Hash() {
+		t.Fatalf("FSM recorded unexpected unsafe head %s", fsm.UnsafeHead().ExecutionPayload.BlockHash)
+	}
+
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if decoded.ExecutionPayload.BlockHash != header.Hash() {
+		t.Fatalf("Decoded unexpected block hash %s", decoded.ExecutionPayload.BlockHash)
+	}
+}
+
+func TestFetchRejectsInvalidBlockArgument(t *testing.T) {
+	if _, err := Fetch(context.Background(), newStubRPC(t, testHeader()), "pending-ish"); err == nil {
+		t.Fatal("Expected invalid block argument to be rejected")
+	}
+}
+
+// stubOptimismAPI serves optimism_syncStatus like op-node does
+type stubOptimismAPI struct{}
+
+func (stubOptimismAPI) SyncStatus() (*eth.SyncStatus, error) {
+	return &eth.SyncStatus{UnsafeL2: eth.L2BlockRef{Number: 1234, Hash: common.HexToHash("0x1234")}}, nil
+}
+
+func TestFetchRejectsOpNode(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("optimism", stubOptimismAPI{}); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	defer server.Stop()
+
+	_, err := Fetch(context.Background(), httpServer.URL, "latest")
+	if err == nil || !strings.Contains(err.Error(), "op-node rollup RPC endpoint") {
+		t.Fatalf("Expected an op-node endpoint to be detected, got: %v", err)
+	}
+}
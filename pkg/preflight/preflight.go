@@ -0,0 +1,61 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/golem-base/op-conductor-init/pkg/config"
+)
+
+// Check resolves the host of every node and dials its consensus port.
+// All nodes are checked concurrently and every failure is reported at once.
+func Check(ctx context.Context, nodes []config.NodeConfig, timeout time.Duration) error {
+	errs := make([]error, len(nodes))
+
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node config.NodeConfig) {
+			defer wg.Done()
+			errs[i] = checkNode(ctx, node, timeout)
+		}(i, node)
+	}
+	wg.Wait()
+
+	// Report in node order regardless of which check finished first
+	var result *multierror.Error
+	for _, err := range errs {
+		if err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result.ErrorOrNil()
+}
+
+func checkNode(ctx context.Context, node config.NodeConfig, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	host, _, err := net.SplitHostPort(node.Address)
+	if err != nil {
+		return fmt.Errorf("%s: invalid address %s: %w", node.ServerID, node.Address, err)
+	}
+
+	if net.ParseIP(host) == nil {
+		if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+			return fmt.Errorf("%s: failed to resolve %s: %w", node.ServerID, host, err)
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", node.Address)
+	if err != nil {
+		return fmt.Errorf("%s: failed to connect to %s: %w", node.ServerID, node.Address, err)
+	}
+	return conn.Close()
+}
@@ -0,0 +1,47 @@
+package preflight
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golem-base/op-conductor-init/pkg/config"
+)
+
+func TestCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// Reserve a port and release it so nothing listens there
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	nodes := []config.NodeConfig{
+		{ServerID: "sequencer-1", Address: listener.Addr().String()},
+		{ServerID: "sequencer-2", Address: closedAddr},
+		{ServerID: "sequencer-3", Address: "does-not-exist.invalid:50050"},
+	}
+
+	err = Check(context.Background(), nodes, 2*time.Second)
+	if err == nil {
+		t.Fatal("Expected preflight to fail")
+	}
+	if strings.Contains(err.Error(), "sequencer-1") {
+		t.Errorf("Expected sequencer-1 to pass, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "sequencer-2: failed to connect") {
+		t.Errorf("Expected sequencer-2 to fail to connect, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "sequencer-3: failed to resolve") {
+		t.Errorf("Expected sequencer-3 to fail to resolve, got: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CloneNode creates the state of a new node from the log store and snapshots of a stopped node.
+// The new node starts in the given term without a vote, so it holds exactly the log of its
+// donor but never claims to have taken part in an election.
+func CloneNode(srcDir, dstDir, serverID string, term uint64) error {
+	if err := os.MkdirAll(dstDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create node directory: %w", err)
+	}
+
+	// Opening the donor's store for writing guarantees that no op-conductor is using it
+	logs, err := openWritable(filepath.Join(srcDir, LogStoreFile))
+	if err != nil {
+		return fmt.Errorf("failed to open log store: %w", err)
+	}
+	err = copyFile(filepath.Join(srcDir, LogStoreFile), filepath.Join(dstDir, LogStoreFile))
+	logs.Close()
+	if err != nil {
+		return fmt.Errorf("failed to copy log store: %w", err)
+	}
+
+	snapshots, err := ReadSnapshots(srcDir)
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		dst := filepath.Join(dstDir, SnapshotsDir, snapshot.ID)
+		if err := os.MkdirAll(dst, 0o755); err != nil {
+			return fmt.Errorf("failed to create snapshot directory: %w", err)
+		}
+		files, err := os.ReadDir(snapshot.Dir)
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot %s: %w", snapshot.ID, err)
+		}
+		for _, file := range files {
+			if err := copyFile(filepath.Join(snapshot.Dir, file.Name()), filepath.Join(dst, file.Name())); err != nil {
+				return fmt.Errorf("failed to copy snapshot %s: %w", snapshot.ID, err)
+			}
+		}
+	}
+
+	return CreateStableStore(dstDir, serverID, term, false)
+}
+
+// copyFile copies a regular file and syncs the copy to disk
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,19 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/raft"
+)
+
+// DecodeConfiguration decodes the data of a raft.LogConfiguration entry.
+// Unlike raft.DecodeConfiguration it returns an error instead of panicking on malformed data.
+func DecodeConfiguration(data []byte) (configuration raft.Configuration, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return raft.DecodeConfiguration(data), nil
+}
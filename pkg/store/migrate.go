@@ -0,0 +1,169 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-msgpack/v2/codec"
+	"github.com/hashicorp/raft"
+)
+
+// AddressRewrite returns the new address of a server, or the address unchanged to keep it
+type AddressRewrite func(id raft.ServerID, address raft.ServerAddress) raft.ServerAddress
+
+// RewriteResult counts the configurations rewritten in the state of a node
+type RewriteResult struct {
+	Entries   int
+	Snapshots int
+}
+
+// RewriteConfiguration applies rewrite to every server of a configuration.
+// changed reports whether any address differs from before.
+func RewriteConfiguration(configuration raft.Configuration, rewrite AddressRewrite) (next raft.Configuration, changed bool) {
+	next = configuration.Clone()
+	for i, server := range next.Servers {
+		if address := rewrite(server.ID, server.Address); address != server.Address {
+			next.Servers[i].Address = address
+			changed = true
+		}
+	}
+	return next, changed
+}
+
+// RewriteAddresses rewrites the server addresses of every configuration entry and snapshot
+// configuration of a stopped node. Server IDs, suffrage, indices and terms are kept, and the
+// changed log entries are stored in a single transaction.
+func RewriteAddresses(nodeDir string, rewrite AddressRewrite) (*RewriteResult, error) {
+	result := &RewriteResult{}
+
+	logs, err := openWritable(filepath.Join(nodeDir, LogStoreFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log store: %w", err)
+	}
+	defer logs.Close()
+
+	firstIndex, err := logs.FirstIndex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read first index: %w", err)
+	}
+	lastIndex, err := logs.LastIndex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last index: %w", err)
+	}
+
+	var changed []*raft.Log
+	for index := firstIndex; index > 0 && index <= lastIndex; index++ {
+		entry := &raft.Log{}
+		if err := logs.GetLog(index, entry); err != nil {
+			return nil, fmt.Errorf("failed to read log entry %d: %w", index, err)
+		}
+		if entry.Type != raft.LogConfiguration {
+			continue
+		}
+		configuration, err := DecodeConfiguration(entry.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode configuration entry %d: %w", index, err)
+		}
+		if next, ok := RewriteConfiguration(configuration, rewrite); ok {
+			entry.Data = raft.EncodeConfiguration(next)
+			changed = append(changed, entry)
+		}
+	}
+	if len(changed) > 0 {
+		if err := logs.StoreLogs(changed); err != nil {
+			return nil, fmt.Errorf("failed to store rewritten configuration entries: %w", err)
+		}
+	}
+	result.Entries = len(changed)
+
+	snapshots, err := ReadSnapshots(nodeDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Err != nil {
+			return nil, fmt.Errorf("failed to read snapshot %s: %w", snapshot.ID, snapshot.Err)
+		}
+		ok, err := rewriteSnapshotMeta(snapshot.Dir, rewrite)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewrite snapshot %s: %w", snapshot.ID, err)
+		}
+		if ok {
+			result.Snapshots++
+		}
+	}
+
+	return result, nil
+}
+
+// snapshotMeta is the meta.json of a FileSnapshotStore snapshot
+type snapshotMeta struct {
+	raft.SnapshotMeta
+	CRC []byte
+}
+
+// rewriteSnapshotMeta rewrites the configuration in a snapshot's meta.json, replacing the file atomically.
+// The legacy peers field is re-encoded the way raft writes it, so both views of the membership agree.
+func rewriteSnapshotMeta(snapshotDir string, rewrite AddressRewrite) (bool, error) {
+	path := filepath.Join(snapshotDir, "meta.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	var meta snapshotMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return false, err
+	}
+
+	next, ok := RewriteConfiguration(meta.Configuration, rewrite)
+	if !ok {
+		return false, nil
+	}
+	meta.Configuration = next
+	if meta.Peers, err = encodePeers(next); err != nil {
+		return false, err
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(&meta); err != nil {
+		return false, err
+	}
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return false, err
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return false, err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return false, err
+	}
+	if err := file.Close(); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, path)
+}
+
+// encodePeers encodes the voters in raft's legacy peers format. Both the network transport
+// op-conductor uses and the in-memory transport encode a peer as its address.
+func encodePeers(configuration raft.Configuration) ([]byte, error) {
+	var peers [][]byte
+	for _, server := range configuration.Servers {
+		if server.Suffrage == raft.Voter {
+			peers = append(peers, []byte(server.Address))
+		}
+	}
+
+	var buf bytes.Buffer
+	handle := codec.MsgpackHandle{BasicHandle: codec.BasicHandle{TimeNotBuiltin: true}}
+	if err := codec.NewEncoder(&buf, &handle).Encode(peers); err != nil {
+		return nil, fmt.Errorf("failed to encode peers: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/raft"
+)
+
+func TestRewriteAddresses(t *testing.T) {
+	nodeDir := t.TempDir()
+
+	configuration := raft.Configuration{
+		Servers: []raft.Server{
+			{Suffrage: raft.Voter, ID: "server1", Address: "server1.old:50050"},
+			{Suffrage: raft.Voter, ID: "server2", Address: "server2.old:50050"},
+			{Suffrage: raft.Nonvoter, ID: "server3", Address: "server3.old:50050"},
+		},
+	}
+	entries := []*raft.Log{
+		{Index: 4, Term: 2, Type: raft.LogConfiguration, Data: raft.EncodeConfiguration(configuration)},
+		{Index: 5, Term: 2, Type: raft.LogCommand, Data: encodedPayload(t, 11)},
+	}
+	if err := CreateLogStore(nodeDir, entries...); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateSnapshot(nodeDir, 3, 2, configuration, 1, []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+
+	rewrite := func(_ raft.ServerID, address raft.ServerAddress) raft.ServerAddress {
+		return raft.ServerAddress(strings.Replace(string(address), ".old:", ".new:", 1))
+	}
+	result, err := RewriteAddresses(nodeDir, rewrite)
+	if err != nil {
+		t.Fatalf("Failed to rewrite addresses: %v", err)
+	}
+	if result.Entries != 1 || result.Snapshots != 1 {
+		t.Fatalf("Expected one entry and one snapshot to be rewritten, got %+v", result)
+	}
+
+	expected, _ := RewriteConfiguration(configuration, rewrite)
+	logs, err := ReadLogStore(filepath.Join(nodeDir, LogStoreFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs.Entries) != 2 || logs.Entries[0].Log.Index != 4 || logs.Entries[0].Log.Term != 2 {
+		t.Fatalf("Expected indices and terms to be kept, got %+v", logs.Entries)
+	}
+	if got, _ := DecodeConfiguration(logs.Entries[0].Log.Data); got.Servers[2] != expected.Servers[2] {
+		t.Fatalf("Expected %+v, got %+v", expected.Servers[2], got.Servers[2])
+	}
+
+	snapshots, err := ReadSnapshots(nodeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Err != nil || snapshots[0].Index != 3 || snapshots[0].Configuration.Servers[0].Address != "server1.new:50050" {
+		t.Fatalf("Expected the snapshot configuration to be rewritten, got %+v", snapshots)
+	}
+
+	// The peers field must match what raft itself writes for the new configuration
+	otherDir := t.TempDir()
+	if err := CreateSnapshot(otherDir, 3, 2, expected, 1, []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+	others, err := ReadSnapshots(otherDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(snapshots[0].Peers, others[0].Peers) {
+		t.Fatalf("Expected peers %x, got %x", others[0].Peers, snapshots[0].Peers)
+	}
+
+	result, err = RewriteAddresses(nodeDir, rewrite)
+	if err != nil || result.Entries != 0 || result.Snapshots != 0 {
+		t.Fatalf("Expected a second rewrite to change nothing, got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,325 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+	boltdb "github.com/hashicorp/raft-boltdb/v2"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/golem-base/op-conductor-init/pkg/payload"
+)
+
+// State file and directory names inside a node directory, as used by op-conductor
+const (
+	StableStoreFile = "raft-stable.db"
+	LogStoreFile    = "raft-log.db"
+	SnapshotsDir    = "snapshots"
+)
+
+// Stable store keys used by HashiCorp Raft
+var (
+	keyCurrentTerm  = []byte("CurrentTerm")
+	keyLastVoteTerm = []byte("LastVoteTerm")
+	keyLastVoteCand = []byte("LastVoteCand")
+)
+
+// StableState holds the values recorded in a node's stable store
+type StableState struct {
+	CurrentTerm  uint64
+	LastVoteTerm uint64
+	LastVoteCand string
+	HasVote      bool
+}
+
+// LogEntry is a single entry read from a log store.
+// Log is nil when the entry is missing or cannot be decoded, in which case Err is set.
+type LogEntry struct {
+	Index uint64
+	Log   *raft.Log
+	Err   error
+}
+
+// LogState holds the index range and the entries of a node's log store
+type LogState struct {
+	FirstIndex uint64
+	LastIndex  uint64
+	Entries    []LogEntry
+}
+
+// Snapshot is the metadata of a snapshot found in a node's snapshots directory.
+// Err is set when the metadata cannot be read.
+type Snapshot struct {
+	raft.SnapshotMeta
+	Dir string
+	Err error
+}
+
+// openReadOnly opens a raft-boltdb store without modifying it
+func openReadOnly(path string) (*boltdb.BoltStore, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return boltdb.New(boltdb.Options{
+		Path:        path,
+		BoltOptions: &bolt.Options{ReadOnly: true, Timeout: time.Second},
+	})
+}
+
+// ReadStableStore reads the term and vote information from a stable store
+func ReadStableStore(path string) (*StableState, error) {
+	store, err := openReadOnly(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stable store: %w", err)
+	}
+	defer store.Close()
+
+	state := &StableState{}
+	if state.CurrentTerm, err = getUint64(store, keyCurrentTerm); err != nil {
+		return nil, fmt.Errorf("failed to read current term: %w", err)
+	}
+	if state.LastVoteTerm, err = getUint64(store, keyLastVoteTerm); err != nil {
+		return nil, fmt.Errorf("failed to read last vote term: %w", err)
+	}
+
+	cand, err := store.Get(keyLastVoteCand)
+	switch {
+	case errors.Is(err, boltdb.ErrKeyNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read last vote candidate: %w", err)
+	default:
+		state.LastVoteCand = string(cand)
+	}
+	state.HasVote = state.LastVoteCand != ""
+
+	return state, nil
+}
+
+// getUint64 reads a uint64 value, treating a missing key as zero
+func getUint64(store *boltdb.BoltStore, key []byte) (uint64, error) {
+	val, err := store.GetUint64(key)
+	if errors.Is(err, boltdb.ErrKeyNotFound) {
+		return 0, nil
+	}
+	return val, err
+}
+
+// ReadLogStore reads every entry of a log store through raft-boltdb's own decoding.
+// Missing or undecodable entries are reported in the returned entries rather than aborting the read.
+func ReadLogStore(path string) (*LogState, error) {
+	store, err := openReadOnly(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log store: %w", err)
+	}
+	defer store.Close()
+
+	state := &LogState{}
+	if state.FirstIndex, err = store.FirstIndex(); err != nil {
+		return nil, fmt.Errorf("failed to read first index: %w", err)
+	}
+	if state.LastIndex, err = store.LastIndex(); err != nil {
+		return nil, fmt.Errorf("failed to read last index: %w", err)
+	}
+	if state.LastIndex == 0 {
+		return state, nil
+	}
+
+	for index := state.FirstIndex; index <= state.LastIndex; index++ {
+		entry := LogEntry{Index: index}
+		log := &raft.Log{}
+		if err := store.GetLog(index, log); err != nil {
+			entry.Err = err
+		} else {
+			entry.Log = log
+		}
+		state.Entries = append(state.Entries, entry)
+	}
+
+	return state, nil
+}
+
+// ReadSnapshots reads the metadata of all snapshots in the node directory, newest first.
+// A missing snapshots directory yields no snapshots.
+func ReadSnapshots(nodeDir string) ([]*Snapshot, error) {
+	snapshotsDir := filepath.Join(nodeDir, SnapshotsDir)
+	dirs, err := os.ReadDir(snapshotsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan snapshots directory: %w", err)
+	}
+
+	var snapshots []*Snapshot
+	for _, dir := range dirs {
+		// Ignore files and snapshots that were never finalized
+		if !dir.IsDir() || strings.HasSuffix(dir.Name(), ".tmp") {
+			continue
+		}
+
+		snapshot := &Snapshot{Dir: filepath.Join(snapshotsDir, dir.Name())}
+		snapshot.ID = dir.Name()
+		data, err := os.ReadFile(filepath.Join(snapshot.Dir, "meta.json"))
+		if err == nil {
+			err = json.Unmarshal(data, &snapshot.SnapshotMeta)
+		}
+		snapshot.Err = err
+		snapshots = append(snapshots, snapshot)
+	}
+
+	// Same ordering as raft's FileSnapshotStore: newest first
+	sort.Slice(snapshots, func(i, j int) bool {
+		a, b := snapshots[i], snapshots[j]
+		if a.Term != b.Term {
+			return a.Term > b.Term
+		}
+		if a.Index != b.Index {
+			return a.Index > b.Index
+		}
+		return a.ID > b.ID
+	})
+
+	return snapshots, nil
+}
+
+// Position is a log index and term
+type Position struct {
+	Index uint64
+	Term  uint64
+}
+
+// LastPosition returns the highest log index and term recorded anywhere in a node's state:
+// the current term of the stable store, the last log entry and the snapshots.
+// Only the last log entry is read, so this stays cheap on long logs.
+func LastPosition(nodeDir string) (*Position, error) {
+	position := &Position{}
+
+	stable, err := ReadStableStore(filepath.Join(nodeDir, StableStoreFile))
+	if err != nil {
+		return nil, err
+	}
+	position.Term = stable.CurrentTerm
+
+	logs, err := openReadOnly(filepath.Join(nodeDir, LogStoreFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log store: %w", err)
+	}
+	defer logs.Close()
+	lastIndex, err := logs.LastIndex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last index: %w", err)
+	}
+	if lastIndex > 0 {
+		last := &raft.Log{}
+		if err := logs.GetLog(lastIndex, last); err != nil {
+			return nil, fmt.Errorf("failed to read last log entry %d: %w", lastIndex, err)
+		}
+		position.Index = lastIndex
+		position.Term = max(position.Term, last.Term)
+	}
+
+	snapshots, err := ReadSnapshots(nodeDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Err != nil {
+			return nil, fmt.Errorf("failed to read snapshot %s: %w", snapshot.ID, snapshot.Err)
+		}
+		position.Index = max(position.Index, snapshot.Index)
+		position.Term = max(position.Term, snapshot.Term)
+	}
+
+	return position, nil
+}
+
+// LatestConfiguration returns the most recent membership configuration found in the
+// log entries or snapshots, together with the index it was recorded at.
+// ok is false when no configuration could be found.
+func LatestConfiguration(logs *LogState, snapshots []*Snapshot) (configuration raft.Configuration, index uint64, ok bool) {
+	for _, snapshot := range snapshots {
+		if snapshot.Err == nil && snapshot.ConfigurationIndex > index {
+			configuration, index, ok = snapshot.Configuration, snapshot.ConfigurationIndex, true
+		}
+	}
+
+	if logs != nil {
+		for _, entry := range logs.Entries {
+			if entry.Log == nil || entry.Log.Type != raft.LogConfiguration || entry.Index <= index {
+				continue
+			}
+			if decoded, err := DecodeConfiguration(entry.Log.Data); err == nil {
+				configuration, index, ok = decoded, entry.Index, true
+			}
+		}
+	}
+
+	return configuration, index, ok
+}
+
+// ReadSnapshotData reads the FSM state stored in a snapshot
+func ReadSnapshotData(snapshot *Snapshot) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(snapshot.Dir, "state.bin"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s state: %w", snapshot.ID, err)
+	}
+	return data, nil
+}
+
+// UnsafeHead is an unsafe head payload recorded on disk and where it was found
+type UnsafeHead struct {
+	Envelope *eth.ExecutionPayloadEnvelope
+	// Index is the log index of the command entry or snapshot the payload was read from
+	Index    uint64
+	Snapshot string
+}
+
+// LatestUnsafeHead replays the newest readable snapshot and the log commands after it the same
+// way op-conductor's consensus FSM does, and returns the resulting unsafe head.
+// It returns nil when no payload is recorded.
+func LatestUnsafeHead(logs *LogState, snapshots []*Snapshot) *UnsafeHead {
+	var head *UnsafeHead
+	var snapshotIndex uint64
+
+	for _, snapshot := range snapshots {
+		if snapshot.Err != nil {
+			continue
+		}
+		data, err := ReadSnapshotData(snapshot)
+		if err != nil {
+			continue
+		}
+		if envelope, err := payload.Decode(data); err == nil {
+			head = &UnsafeHead{Envelope: envelope, Index: snapshot.Index, Snapshot: snapshot.ID}
+		}
+		snapshotIndex = snapshot.Index
+		break
+	}
+
+	if logs == nil {
+		return head
+	}
+	for _, entry := range logs.Entries {
+		if entry.Log == nil || entry.Log.Type != raft.LogCommand || entry.Index <= snapshotIndex {
+			continue
+		}
+		envelope, err := payload.Decode(entry.Log.Data)
+		if err != nil {
+			continue
+		}
+		// The FSM only moves the unsafe head forward
+		if head == nil || head.Envelope.ExecutionPayload.BlockNumber < envelope.ExecutionPayload.BlockNumber {
+			head = &UnsafeHead{Envelope: envelope, Index: entry.Index}
+		}
+	}
+
+	return head
+}
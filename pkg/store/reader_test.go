@@ -0,0 +1,166 @@
+package store
+
+import (
+	"encoding/binary"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/hashicorp/raft"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/golem-base/op-conductor-init/pkg/payload"
+)
+
+func TestReadState(t *testing.T) {
+	nodeDir := t.TempDir()
+
+	configuration := raft.Configuration{
+		Servers: []raft.Server{
+			{Suffrage: raft.Voter, ID: "server1", Address: "127.0.0.1:8300"},
+			{Suffrage: raft.Nonvoter, ID: "server2", Address: "127.0.0.1:8301"},
+		},
+	}
+	entries := []*raft.Log{
+		{Index: 1, Term: 3, Type: raft.LogConfiguration, Data: raft.EncodeConfiguration(configuration)},
+		{Index: 2, Term: 3, Type: raft.LogNoop},
+	}
+
+	if err := CreateStableStore(nodeDir, "server1", 3, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateLogStore(nodeDir, entries...); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt a third entry the way a foreign writer could
+	db, err := bolt.Open(filepath.Join(nodeDir, LogStoreFile), 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, 3)
+		return tx.Bucket([]byte("logs")).Put(key, []byte{0xff, 0x00, 0x01})
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stable, err := ReadStableStore(filepath.Join(nodeDir, StableStoreFile))
+	if err != nil {
+		t.Fatalf("Failed to read stable store: %v", err)
+	}
+	if stable.CurrentTerm != 3 || !stable.HasVote || stable.LastVoteCand != "server1" {
+		t.Fatalf("Unexpected stable state: %+v", stable)
+	}
+
+	logs, err := ReadLogStore(filepath.Join(nodeDir, LogStoreFile))
+	if err != nil {
+		t.Fatalf("Failed to read log store: %v", err)
+	}
+	if logs.FirstIndex != 1 || logs.LastIndex != 3 || len(logs.Entries) != 3 {
+		t.Fatalf("Unexpected log range: first=%d last=%d entries=%d", logs.FirstIndex, logs.LastIndex, len(logs.Entries))
+	}
+	if logs.Entries[1].Log == nil || logs.Entries[1].Log.Type != raft.LogNoop {
+		t.Fatalf("Expected entry 2 to be a noop, got %+v", logs.Entries[1])
+	}
+	if logs.Entries[2].Err == nil {
+		t.Fatal("Expected entry 3 to be reported as undecodable")
+	}
+
+	latest, index, ok := LatestConfiguration(logs, nil)
+	if !ok || index != 1 {
+		t.Fatalf("Expected configuration at index 1, got index=%d ok=%v", index, ok)
+	}
+	if latest.Servers[1].Suffrage != raft.Nonvoter {
+		t.Fatalf("Expected server2 to be a nonvoter, got %s", latest.Servers[1].Suffrage)
+	}
+}
+
+func TestReadSnapshots(t *testing.T) {
+	nodeDir := t.TempDir()
+
+	configuration := raft.Configuration{
+		Servers: []raft.Server{{Suffrage: raft.Voter, ID: "server1", Address: "127.0.0.1:8300"}},
+	}
+	if err := CreateSnapshot(nodeDir, 100, 4, configuration, 90, []byte("state")); err != nil {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+
+	snapshots, err := ReadSnapshots(nodeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Err != nil {
+		t.Fatalf("Expected one readable snapshot, got %+v", snapshots)
+	}
+	if snapshots[0].Index != 100 || snapshots[0].Term != 4 || snapshots[0].Size != int64(len("state")) {
+		t.Fatalf("Unexpected snapshot metadata: %+v", snapshots[0].SnapshotMeta)
+	}
+
+	_, index, ok := LatestConfiguration(&LogState{}, snapshots)
+	if !ok || index != 90 {
+		t.Fatalf("Expected configuration at index 90, got index=%d ok=%v", index, ok)
+	}
+}
+
+func encodedPayload(t *testing.T, number uint64) []byte {
+	t.Helper()
+	beaconRoot := common.HexToHash("0xbeac0b")
+	data, err := payload.Encode(&eth.ExecutionPayloadEnvelope{
+		ParentBeaconBlockRoot: &beaconRoot,
+		ExecutionPayload: &eth.ExecutionPayload{
+			BlockNumber:   hexutil.Uint64(number),
+			BlockHash:     common.BigToHash(new(big.Int).SetUint64(number)),
+			Withdrawals:   &types.Withdrawals{},
+			BlobGasUsed:   new(hexutil.Uint64),
+			ExcessBlobGas: new(hexutil.Uint64),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestLatestUnsafeHead(t *testing.T) {
+	nodeDir := t.TempDir()
+
+	configuration := raft.Configuration{
+		Servers: []raft.Server{{Suffrage: raft.Voter, ID: "server1", Address: "127.0.0.1:8300"}},
+	}
+	if err := CreateSnapshot(nodeDir, 10, 1, configuration, 10, encodedPayload(t, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateLogStore(nodeDir,
+		&raft.Log{Index: 11, Term: 1, Type: raft.LogCommand, Data: encodedPayload(t, 102)},
+		&raft.Log{Index: 12, Term: 1, Type: raft.LogCommand, Data: encodedPayload(t, 101)},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	logs, err := ReadLogStore(filepath.Join(nodeDir, LogStoreFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots, err := ReadSnapshots(nodeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The FSM never moves the unsafe head backwards
+	head := LatestUnsafeHead(logs, snapshots)
+	if head == nil || uint64(head.Envelope.ExecutionPayload.BlockNumber) != 102 || head.Index != 11 {
+		t.Fatalf("Expected unsafe head 102 from entry 11, got %+v", head)
+	}
+
+	if head := LatestUnsafeHead(nil, snapshots); head == nil || head.Snapshot == "" {
+		t.Fatalf("Expected unsafe head from snapshot, got %+v", head)
+	}
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/raft"
+	boltdb "github.com/hashicorp/raft-boltdb/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+// openWritable opens an existing raft-boltdb store for writing.
+// It fails instead of blocking when another process, such as a running op-conductor, holds the store.
+func openWritable(path string) (*boltdb.BoltStore, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	store, err := boltdb.New(boltdb.Options{
+		Path:        path,
+		BoltOptions: &bolt.Options{Timeout: time.Second},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w (is op-conductor still running?)", err)
+	}
+	return store, nil
+}
+
+// AppendLog appends an entry to the log store of a stopped node and raises the node's
+// current term to the entry's term if it is lower
+func AppendLog(nodeDir string, entry *raft.Log) error {
+	logs, err := openWritable(filepath.Join(nodeDir, LogStoreFile))
+	if err != nil {
+		return fmt.Errorf("failed to open log store: %w", err)
+	}
+	defer logs.Close()
+
+	lastIndex, err := logs.LastIndex()
+	if err != nil {
+		return fmt.Errorf("failed to read last index: %w", err)
+	}
+	if entry.Index <= lastIndex {
+		return fmt.Errorf("entry index %d does not follow last index %d", entry.Index, lastIndex)
+	}
+
+	stable, err := openWritable(filepath.Join(nodeDir, StableStoreFile))
+	if err != nil {
+		return fmt.Errorf("failed to open stable store: %w", err)
+	}
+	defer stable.Close()
+
+	currentTerm, err := getUint64(stable, keyCurrentTerm)
+	if err != nil {
+		return fmt.Errorf("failed to read current term: %w", err)
+	}
+
+	if err := logs.StoreLog(entry); err != nil {
+		return fmt.Errorf("failed to store log entry: %w", err)
+	}
+	if entry.Term > currentTerm {
+		if err := stable.SetUint64(keyCurrentTerm, entry.Term); err != nil {
+			return fmt.Errorf("failed to set current term: %w", err)
+		}
+	}
+
+	return nil
+}
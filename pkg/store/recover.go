@@ -0,0 +1,94 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+
+	"github.com/ethereum-optimism/optimism/op-conductor/consensus"
+)
+
+// Recovery describes a configuration forced onto a node's state by RecoverNode
+type Recovery struct {
+	PreviousTerm  uint64
+	CurrentTerm   uint64
+	SnapshotID    string
+	SnapshotIndex uint64
+	SnapshotTerm  uint64
+}
+
+// RecoverNode forces a new membership configuration onto the state of a stopped node using
+// raft.RecoverCluster. The log is replayed through op-conductor's consensus FSM into a new
+// snapshot carrying the configuration, and the log is compacted. The current term is then
+// raised above every term the node has seen, so peers holding the old view cannot win an
+// election against the recovered cluster.
+func RecoverNode(nodeDir string, configuration raft.Configuration) (*Recovery, error) {
+	logs, err := ReadLogStore(filepath.Join(nodeDir, LogStoreFile))
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := ReadSnapshots(nodeDir)
+	if err != nil {
+		return nil, err
+	}
+	// op-conductor's FSM cannot persist a snapshot without an unsafe head
+	if LatestUnsafeHead(logs, snapshots) == nil {
+		return nil, fmt.Errorf("no unsafe payload recorded in %s, the consensus FSM cannot be snapshotted", nodeDir)
+	}
+
+	logStore, err := openWritable(filepath.Join(nodeDir, LogStoreFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log store: %w", err)
+	}
+	defer logStore.Close()
+
+	stableStore, err := openWritable(filepath.Join(nodeDir, StableStoreFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stable store: %w", err)
+	}
+	defer stableStore.Close()
+
+	snapshotStore, err := raft.NewFileSnapshotStore(nodeDir, SnapshotRetain, io.Discard)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot store: %w", err)
+	}
+
+	_, trans := raft.NewInmemTransport("")
+	defer trans.Close()
+
+	conf := raft.DefaultConfig()
+	conf.LocalID = raft.ServerID(filepath.Base(nodeDir))
+	conf.Logger = hclog.NewNullLogger()
+	fsm := consensus.NewUnsafeHeadTracker(log.NewLogger(log.DiscardHandler()))
+
+	recovery := &Recovery{}
+	if recovery.PreviousTerm, err = getUint64(stableStore, keyCurrentTerm); err != nil {
+		return nil, fmt.Errorf("failed to read current term: %w", err)
+	}
+
+	if err := raft.RecoverCluster(conf, fsm, logStore, stableStore, snapshotStore, trans, configuration); err != nil {
+		return nil, fmt.Errorf("failed to recover cluster: %w", err)
+	}
+
+	recovered, err := snapshotStore.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find recovery snapshot: %w", err)
+	}
+	if len(recovered) == 0 {
+		return nil, fmt.Errorf("no snapshot found after recovery")
+	}
+	recovery.SnapshotID = recovered[0].ID
+	recovery.SnapshotIndex = recovered[0].Index
+	recovery.SnapshotTerm = recovered[0].Term
+
+	recovery.CurrentTerm = max(recovery.PreviousTerm, recovery.SnapshotTerm) + 1
+	if err := stableStore.SetUint64(keyCurrentTerm, recovery.CurrentTerm); err != nil {
+		return nil, fmt.Errorf("failed to set current term: %w", err)
+	}
+
+	return recovery, nil
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	boltdb "github.com/hashicorp/raft-boltdb/v2"
+
+	"github.com/ethereum-optimism/optimism/op-conductor/consensus"
+)
+
+func TestRecoverNode(t *testing.T) {
+	nodeDir := filepath.Join(t.TempDir(), "server1")
+	if err := os.Mkdir(nodeDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	configuration := raft.Configuration{
+		Servers: []raft.Server{
+			{Suffrage: raft.Voter, ID: "server1", Address: "server1"},
+			{Suffrage: raft.Voter, ID: "server2", Address: "server2"},
+			{Suffrage: raft.Voter, ID: "server3", Address: "server3"},
+		},
+	}
+	entries := []*raft.Log{
+		{Index: 1, Term: 2, Type: raft.LogConfiguration, Data: raft.EncodeConfiguration(configuration)},
+		{Index: 2, Term: 2, Type: raft.LogCommand, Data: encodedPayload(t, 11)},
+		{Index: 3, Term: 3, Type: raft.LogCommand, Data: encodedPayload(t, 12)},
+	}
+	if err := CreateStableStore(nodeDir, "server2", 3, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateLogStore(nodeDir, entries...); err != nil {
+		t.Fatal(err)
+	}
+
+	// server2 and server3 are lost: server1 alone can never reach quorum again
+	recovered := raft.Configuration{
+		Servers: []raft.Server{{Suffrage: raft.Voter, ID: "server1", Address: "server1"}},
+	}
+	recovery, err := RecoverNode(nodeDir, recovered)
+	if err != nil {
+		t.Fatalf("Failed to recover node: %v", err)
+	}
+	if recovery.PreviousTerm != 3 || recovery.CurrentTerm != 4 {
+		t.Fatalf("Expected term to be bumped from 3 to 4, got %+v", recovery)
+	}
+	if recovery.SnapshotIndex != 3 || recovery.SnapshotTerm != 3 {
+		t.Fatalf("Expected recovery snapshot at index 3, term 3, got %+v", recovery)
+	}
+
+	// Start the recovered node the way op-conductor does and check it elects itself
+	logStore, err := boltdb.NewBoltStore(filepath.Join(nodeDir, LogStoreFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logStore.Close()
+	stableStore, err := boltdb.NewBoltStore(filepath.Join(nodeDir, StableStoreFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stableStore.Close()
+	snapshotStore, err := raft.NewFileSnapshotStore(nodeDir, SnapshotRetain, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, trans := raft.NewInmemTransport("server1")
+
+	conf := raft.DefaultConfig()
+	conf.LocalID = "server1"
+	conf.Logger = hclog.NewNullLogger()
+	conf.HeartbeatTimeout = 50 * time.Millisecond
+	conf.ElectionTimeout = 50 * time.Millisecond
+	conf.LeaderLeaseTimeout = 50 * time.Millisecond
+	fsm := consensus.NewUnsafeHeadTracker(log.NewLogger(log.DiscardHandler()))
+
+	r, err := raft.NewRaft(conf, fsm, logStore, stableStore, snapshotStore, trans)
+	if err != nil {
+		t.Fatalf("Failed to start recovered node: %v", err)
+	}
+	defer r.Shutdown()
+
+	select {
+	case isLeader := <-r.LeaderCh():
+		if !isLeader {
+			t.Fatal("Expected recovered node to become leader")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Recovered node did not become leader")
+	}
+
+	if term := r.Stats()["term"]; term != "5" {
+		t.Fatalf("Expected the first election after recovery to be in term 5, got %s", term)
+	}
+	future := r.GetConfiguration()
+	if err := future.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if servers := future.Configuration().Servers; len(servers) != 1 || servers[0].ID != "server1" {
+		t.Fatalf("Expected recovered configuration, got %+v", servers)
+	}
+	if head := fsm.UnsafeHead(); head == nil || head.ExecutionPayload.BlockNumber != 12 {
+		t.Fatalf("Expected unsafe head 12 to survive recovery, got %+v", head)
+	}
+}
+
+func TestRecoverNodeWithoutPayload(t *testing.T) {
+	nodeDir := t.TempDir()
+
+	configuration := raft.Configuration{
+		Servers: []raft.Server{{Suffrage: raft.Voter, ID: "server1", Address: "server1"}},
+	}
+	if err := CreateStableStore(nodeDir, "server1", 1, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateLogStore(nodeDir, &raft.Log{Index: 1, Term: 1, Type: raft.LogConfiguration, Data: raft.EncodeConfiguration(configuration)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := RecoverNode(nodeDir, configuration); err == nil {
+		t.Fatal("Expected recovery without an unsafe payload to fail")
+	}
+}
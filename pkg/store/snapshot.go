@@ -0,0 +1,43 @@
+package store
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/raft"
+)
+
+// SnapshotRetain is the number of snapshots op-conductor retains in its FileSnapshotStore
+const SnapshotRetain = 1
+
+// CreateSnapshot writes a FileSnapshotStore compatible snapshot into nodeDir/snapshots.
+// The snapshot records the membership configuration at the given index and term,
+// and data is stored as the FSM state (it may be empty).
+func CreateSnapshot(nodeDir string, index, term uint64, configuration raft.Configuration, configurationIndex uint64, data []byte) error {
+	snapshots, err := raft.NewFileSnapshotStore(nodeDir, SnapshotRetain, io.Discard)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot store: %w", err)
+	}
+
+	// The transport is only used to encode the legacy peers field of the snapshot metadata
+	_, trans := raft.NewInmemTransport("")
+	defer trans.Close()
+
+	sink, err := snapshots.Create(raft.SnapshotVersionMax, index, term, configuration, configurationIndex, trans)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	if _, err := sink.Write(data); err != nil {
+		if cerr := sink.Cancel(); cerr != nil {
+			return fmt.Errorf("failed to write snapshot data: %w (cancel failed: %v)", err, cerr)
+		}
+		return fmt.Errorf("failed to write snapshot data: %w", err)
+	}
+
+	if err := sink.Close(); err != nil {
+		return fmt.Errorf("failed to finalize snapshot: %w", err)
+	}
+
+	return nil
+}
@@ -10,7 +10,7 @@ import (
 
 // CreateStableStore creates the stable store for a node using HashiCorp's raft-boltdb
 func CreateStableStore(nodeDir string, serverID string, initialTerm uint64, isLeader bool) error {
-	dbPath := filepath.Join(nodeDir, "raft-stable.db")
+	dbPath := filepath.Join(nodeDir, StableStoreFile)
 
 	// Create stable store using HashiCorp's raft-boltdb
 	store, err := boltdb.NewBoltStore(dbPath)
@@ -37,9 +37,10 @@ func CreateStableStore(nodeDir string, serverID string, initialTerm uint64, isLe
 	return nil
 }
 
-// CreateLogStore creates the log store for a node with initial configuration
-func CreateLogStore(nodeDir string, configEntry *raft.Log) error {
-	dbPath := filepath.Join(nodeDir, "raft-log.db")
+// CreateLogStore creates the log store for a node with the initial configuration
+// and any additional entries that follow it
+func CreateLogStore(nodeDir string, entries ...*raft.Log) error {
+	dbPath := filepath.Join(nodeDir, LogStoreFile)
 
 	// Create log store using HashiCorp's raft-boltdb
 	store, err := boltdb.NewBoltStore(dbPath)
@@ -48,9 +49,9 @@ func CreateLogStore(nodeDir string, configEntry *raft.Log) error {
 	}
 	defer store.Close()
 
-	// Store the configuration entry
-	if err := store.StoreLog(configEntry); err != nil {
-		return fmt.Errorf("failed to store configuration entry: %w", err)
+	// Store the entries in a single transaction
+	if err := store.StoreLogs(entries); err != nil {
+		return fmt.Errorf("failed to store log entries: %w", err)
 	}
 
 	// The raft-boltdb library automatically maintains FirstIndex and LastIndex